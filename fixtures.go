@@ -0,0 +1,239 @@
+// This file serves the pages and toc.json files over local HTTP, so the commands can run without the documentation
+// by setting NTDOCS_BASE_URL to the server
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	pathpkg "path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/cloakwiss/ntdocs/inter"
+	"github.com/cloakwiss/ntdocs/toc"
+	"github.com/cloakwiss/ntdocs/utils"
+)
+
+type fixtureArguments struct {
+	addr, dir      string
+	fromDB, record bool
+	fetcher        inter.FetcherConfig
+}
+
+func parseFixtureArguments(args []string, out *bufio.Writer) (arguments fixtureArguments, er error) {
+	flags := flag.NewFlagSet("serve-fixtures", flag.ContinueOnError)
+	flags.SetOutput(out)
+	fetcherFlags(flags, &arguments.fetcher)
+	flags.StringVar(&arguments.addr, "addr", "127.0.0.1:8080", "Address to listen on")
+	flags.StringVar(&arguments.dir, "dir", "", "Directory of fixtures, pages are at their path with .html i.e. `windows/win32/api/memoryapi/nf-memoryapi-virtualalloc.html`")
+	flags.BoolVar(&arguments.fromDB, "db", false, "Serve the pages in RawHTML and the toc.json in Headers table, after the ones in -dir")
+	flags.BoolVar(&arguments.record, "record", false, "Fetch the files missing in -dir from the documentation and save them there")
+	if er = flags.Parse(args); er != nil {
+		return
+	}
+	if flags.NArg() > 0 {
+		return arguments, fmt.Errorf("unexpected arguments: %v", flags.Args())
+	}
+	if arguments.dir == "" && !arguments.fromDB {
+		return arguments, fmt.Errorf("no fixtures given, use -dir or -db")
+	}
+	if arguments.record && arguments.dir == "" {
+		return arguments, fmt.Errorf("-record needs -dir to save the files")
+	}
+	return
+}
+
+// Serves the fixtures till ctx is done. A request is answered from the directory first, then from the database and
+// at last it is recorded from the documentation, only the enabled ones are tried.
+func serveFixtures(ctx context.Context, db *sql.DB, stdoutbuf *bufio.Writer, args []string) {
+	arguments, er := parseFixtureArguments(args, stdoutbuf)
+	if errors.Is(er, flag.ErrHelp) {
+		return
+	} else if er != nil {
+		fmt.Fprintln(stdoutbuf, er)
+		return
+	}
+
+	server := fixtureServer{dir: arguments.dir}
+	if arguments.fromDB {
+		server.db = db
+		server.rootToc, server.tocs, er = storedTocs(inter.StoredHeaders(db))
+		if er != nil {
+			log.Panicln(er)
+		}
+		fmt.Fprintln(stdoutbuf, len(server.tocs), "toc.json of headers in the database")
+	}
+	if arguments.record {
+		server.recorder = inter.NewFetcher(arguments.fetcher)
+	}
+
+	listener, er := net.Listen("tcp", arguments.addr)
+	if er != nil {
+		fmt.Fprintln(stdoutbuf, er)
+		return
+	}
+	httpServer := &http.Server{Handler: &server}
+	fmt.Fprintf(stdoutbuf, "Serving fixtures at http://%[1]s, run the commands with NTDOCS_BASE_URL=http://%[1]s/en-us\n", listener.Addr())
+	stdoutbuf.Flush()
+
+	served := make(chan error, 1)
+	go func() {
+		served <- httpServer.Serve(listener)
+	}()
+	select {
+	case er := <-served:
+		log.Panicln(er)
+	case <-ctx.Done():
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancel()
+	if er := httpServer.Shutdown(shutdownCtx); er != nil {
+		log.Println(er)
+	}
+}
+
+type fixtureServer struct {
+	dir string
+	// Set only with -db
+	db      *sql.DB
+	rootToc []byte
+	tocs    map[string][]byte
+	// Set only with -record
+	recorder *inter.Fetcher
+}
+
+func (s *fixtureServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	// Same path as in Symbol table, so the locale and the case of the url do not matter
+	path := utils.NormalizePath(pathpkg.Clean("/" + r.URL.Path))
+	if path == "" {
+		http.NotFound(w, r)
+		return
+	}
+	if s.dir != "" && s.serveFile(w, r, path) {
+		return
+	}
+	if s.db != nil && s.serveStored(w, r, path) {
+		return
+	}
+	if s.recorder != nil && s.serveRecorded(w, r, path) {
+		return
+	}
+	log.Printf("Not found: %s\n", r.URL.Path)
+	http.NotFound(w, r)
+}
+
+// File of the fixture in the directory, toc.json files are kept as they are and the pages get .html
+func (s *fixtureServer) fixtureFile(path string) string {
+	if !strings.HasSuffix(path, ".json") {
+		path += ".html"
+	}
+	return filepath.Join(s.dir, filepath.FromSlash(path))
+}
+
+func (s *fixtureServer) serveFile(w http.ResponseWriter, r *http.Request, path string) bool {
+	file, er := os.Open(s.fixtureFile(path))
+	if er != nil {
+		return false
+	}
+	defer file.Close()
+	info, er := file.Stat()
+	if er != nil || info.IsDir() {
+		return false
+	}
+	http.ServeContent(w, r, info.Name(), info.ModTime(), file)
+	return true
+}
+
+func (s *fixtureServer) serveStored(w http.ResponseWriter, r *http.Request, path string) bool {
+	if path == toc.RootPath {
+		http.ServeContent(w, r, "toc.json", time.Time{}, bytes.NewReader(s.rootToc))
+		return true
+	}
+	if blob, found := s.tocs[path]; found {
+		http.ServeContent(w, r, "toc.json", time.Time{}, bytes.NewReader(blob))
+		return true
+	}
+
+	main, contentHash, found, er := inter.StoredPage(s.db, path)
+	if er != nil {
+		log.Println(er)
+		http.Error(w, er.Error(), http.StatusInternalServerError)
+		return true
+	} else if !found {
+		return false
+	}
+	if contentHash != "" {
+		w.Header().Set("ETag", `"`+contentHash+`"`)
+	}
+	// Main content is taken from the second `div.content` of the page like on the documentation
+	var page bytes.Buffer
+	page.WriteString(`<html><body><div class="content"></div>`)
+	page.Write(main)
+	page.WriteString(`</body></html>`)
+	http.ServeContent(w, r, "page.html", time.Time{}, bytes.NewReader(page.Bytes()))
+	return true
+}
+
+// Fetches the file from the documentation and saves it in the directory, so it is replayed from there next time
+func (s *fixtureServer) serveRecorded(w http.ResponseWriter, r *http.Request, path string) bool {
+	body, er := s.recorder.Get(r.Context(), utils.DocumentationUrl(path))
+	if statusError := (*inter.StatusError)(nil); errors.As(er, &statusError) {
+		http.Error(w, er.Error(), statusError.StatusCode)
+		return true
+	} else if er != nil {
+		log.Println(er)
+		http.Error(w, er.Error(), http.StatusBadGateway)
+		return true
+	}
+
+	file := s.fixtureFile(path)
+	if er := os.MkdirAll(filepath.Dir(file), 0o755); er != nil {
+		log.Panicln(er)
+	}
+	if er := os.WriteFile(file, body, 0o644); er != nil {
+		log.Panicln(er)
+	}
+	log.Printf("Recorded %s\n", path)
+	http.ServeContent(w, r, filepath.Base(file), time.Now(), bytes.NewReader(body))
+	return true
+}
+
+// Root TOC listing the stored headers and their toc.json by path. Headers are linked relative to the root TOC, so
+// they resolve under whichever locale it was requested.
+func storedTocs(headers []inter.HeaderTOC) (rootToc []byte, tocs map[string][]byte, er error) {
+	tocs = make(map[string][]byte, len(headers))
+	group := toc.Node{Title: "Headers"}
+	for _, header := range headers {
+		path, internal := utils.ResolveLink("/", storedTocUrl(header))
+		if !internal || !strings.HasSuffix(path, "/toc.json") {
+			log.Printf("Skipping %s: toc.json is not on the documentation\n", header.Name)
+			continue
+		}
+		tocs[path] = header.JsonBlob
+		// Root TOC is at `/<locale>/windows/win32/api/toc.json`
+		href := "../../../" + strings.TrimPrefix(strings.TrimSuffix(path, "toc.json"), "/")
+		group.Children = append(group.Children, toc.Node{Title: header.Name, Href: href})
+	}
+	rootToc, er = json.Marshal(struct {
+		Items []toc.Node `json:"items"`
+	}{[]toc.Node{group}})
+	if er != nil {
+		return nil, nil, fmt.Errorf("cannot make root TOC: %w", er)
+	}
+	return rootToc, tocs, nil
+}
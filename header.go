@@ -1,114 +1,110 @@
-// This file populates the Symbol table by reading from Headers table's JSON blob
+// This file discovers the headers from the TOC of win32 API and populates the Headers and Symbol tables from
+// the toc.json of each header
 package main
 
 import (
+	"bufio"
+	"context"
 	"database/sql"
-	"encoding/json"
+	"fmt"
 	"log"
 	"strings"
 
-	"github.com/k0kubun/pp/v3"
-
+	"github.com/cloakwiss/ntdocs/inter"
+	"github.com/cloakwiss/ntdocs/toc"
 	"github.com/cloakwiss/ntdocs/utils"
 )
 
-// Working with headers' toc.json files
-type HeaderRecord struct {
-	header, name, symbol_type, url string
-}
+// Fetches the root TOC and the toc.json of headers which are not in Headers table, then fills Symbol table from
+// all of them. Running it again only fetches the headers added to TOC since.
+func discoverHeaders(ctx context.Context, db *sql.DB, fetcher *inter.Fetcher, stdoutbuf *bufio.Writer) {
+	rootUrl := utils.SourceUrl(toc.RootPath)
+	input, er := fetcher.Get(ctx, rootUrl)
+	if er != nil {
+		log.Panicf("Cannot fetch root TOC: %v\n", er)
+	}
+	root, er := toc.ParseRoot(input, rootUrl)
+	if er != nil {
+		log.Panicln(er)
+	}
+	reportNodeErrors(stdoutbuf, "root TOC", root.Errors)
 
-// Read url from db and fetches it contents
-// for now we and not covering symbols of type interface
-// but that logic in not encoded in scrapper at the moment
-func fetchHeaderSSymbols() {
-	parseTOCJson := func(input []byte) []HeaderRecord {
-		var full any
-		er := json.Unmarshal(input, &full)
-		if er != nil {
-			log.Fatal(er)
+	fetched := inter.FilledNames(db, "Headers", "name")
+	fmt.Fprintln(stdoutbuf, len(root.Headers), "headers found,", len(fetched), "already fetched")
+	stdoutbuf.Flush()
+	for _, header := range root.Headers {
+		if fetched[header.Name] {
+			continue
 		}
-		var record []HeaderRecord = make([]HeaderRecord, 0, 16)
-		if unwrapped, ok := utils.Cast[map[string]any](full); ok {
-			raw_title, title_present := unwrapped["toc_title"]
-			if !title_present {
-				log.Fatal("Header file name not present")
-			}
-			title, casted := utils.Cast[string](raw_title)
-			if !casted {
-				log.Fatal("Casting of title to string failed")
-			}
-			node_list, nodes_present := unwrapped["children"]
-			if !nodes_present {
-				log.Fatal("Children not present")
-			}
-			if url_list, ok := utils.Cast[[]any](node_list); ok {
-				for _, raw_node := range url_list[1:] {
-					if casted, ok := utils.Cast[map[string]any](raw_node); ok {
-						raw_url, url_found := casted["href"]
-						if !url_found {
-							if _, contains_subtree := casted["children"]; contains_subtree {
-								continue
-							}
-							log.Fatal("Value not found")
-						}
-						raw_key, key_found := casted["toc_title"]
-						if !key_found {
-							log.Fatal("Key not found or not casted")
-						}
-						key, key_ok := utils.Cast[string](raw_key)
-						url, value_ok := utils.Cast[string](raw_url)
-						if !key_ok {
-							log.Fatal("Key not found or not casted")
-						}
-						if !value_ok {
-							log.Fatal("Value not casted")
-						}
-
-						name, symbol_type, found := strings.Cut(strings.Trim(key, " "), " ")
-						if !found {
-							log.Fatal("Some error occured in spliting the record.")
-						}
-						record = append(record, HeaderRecord{header: title, name: name, symbol_type: symbol_type, url: url})
-
-					}
-				}
-			}
+		json_blob, er := fetcher.Get(ctx, header.Url)
+		if ctx.Err() != nil {
+			fmt.Fprintln(stdoutbuf, "Stopped, the headers left are fetched by running it again")
+			return
+		} else if er != nil {
+			log.Printf("Skipping %s: %v\n", header.Name, er)
+			continue
 		}
-		return record
-	}
-	db, err := sql.Open("sqlite3", "./ntdocs.db")
-	if err != nil {
-		log.Fatal("Cannot open ntdocs.db")
+		// Url on the documentation is stored, so Headers stays same whichever source it was fetched from
+		tocUrl := header.Url
+		if path, internal := utils.ResolveLink("/", header.Url); internal {
+			tocUrl = utils.DocumentationUrl(path)
+		}
+		// Fetched toc is stored even when stopped meanwhile
+		if er := inter.AddToHeaders(context.WithoutCancel(ctx), db, header.Name, tocUrl, json_blob); er != nil {
+			log.Panicln(er)
+		}
+		log.Printf("Fetched toc of %s\n", header.Name)
 	}
-	defer db.Close()
+	fillHeaderSymbols(ctx, db, stdoutbuf)
+}
 
-	res, err := db.Query("SELECT name, json_blob FROM Headers;")
-	if err != nil {
-		log.Fatal("Cannot query Headers from ntdocs.db")
+// Url of toc.json of the stored header, headers stored before the discovery have it at the usual place
+func storedTocUrl(header inter.HeaderTOC) string {
+	if header.Url != "" {
+		return header.Url
 	}
-	defer res.Close()
+	return utils.DocumentationUrl("/windows/win32/api/" + strings.TrimSuffix(strings.ToLower(header.Name), ".h") + "/toc.json")
+}
 
-	var (
-		name      string
-		json_blob []byte
-	)
-	stmt, er := db.Prepare("INSERT INTO Symbol VALUES (?, ?, ?, ?)")
-	if er != nil {
-		log.Fatal("Failed to prepare statement")
+// Rebuilds the Symbol table from the toc.json stored in Headers table
+func fillHeaderSymbols(ctx context.Context, db *sql.DB, stdoutbuf *bufio.Writer) {
+	headers := inter.StoredHeaders(db)
+	total, failed := 0, 0
+	for _, header := range headers {
+		if ctx.Err() != nil {
+			fmt.Fprintln(stdoutbuf, "Stopped, symbols of the headers left are not replaced")
+			break
+		}
+		parsed, er := toc.ParseHeader(header.JsonBlob, storedTocUrl(header))
+		if er != nil {
+			fmt.Fprintf(stdoutbuf, "Skipping %s: %v\n", header.Name, er)
+			failed++
+			continue
+		}
+		reportNodeErrors(stdoutbuf, header.Name, parsed.Errors)
+		symbols := make([]inter.SymbolRecord, 0, len(parsed.Symbols))
+		for _, symbol := range parsed.Symbols {
+			// Name from root TOC is used, as the symbols are replaced by it
+			symbols = append(symbols, inter.SymbolRecord{Header: header.Name, Name: symbol.Name, Ttype: string(symbol.Kind), Url: symbol.Path})
+		}
+		if er := inter.ReplaceHeaderSymbols(ctx, db, header.Name, symbols); ctx.Err() != nil {
+			// Transaction is rolled back, so the old symbols of header are kept
+			continue
+		} else if er != nil {
+			log.Panicln(er)
+		}
+		total += len(symbols)
 	}
-	defer stmt.Close()
+	fmt.Fprintln(stdoutbuf, total, "symbols filled from", len(headers)-failed, "headers,", failed, "headers skipped")
+}
 
-	for res.Next() {
-		res.Scan(&name, &json_blob)
-		records := parseTOCJson(json_blob)
-		if len(records) > 0 {
-			for _, record := range records {
-				_, er := stmt.Exec(record.header, record.name, record.symbol_type, record.url)
-				if er != nil {
-					pp.Printf("Failed due to %v\nOn the write: %v\n", er, record)
-					return
-				}
-			}
-		}
+// Nodes which are skipped, listed so they can be checked by hand
+func reportNodeErrors(stdoutbuf *bufio.Writer, source string, nodeErrors []toc.NodeError) {
+	if len(nodeErrors) == 0 {
+		return
+	}
+	fmt.Fprintf(stdoutbuf, "%d nodes of %s skipped:\n", len(nodeErrors), source)
+	for _, er := range nodeErrors {
+		fmt.Fprintf(stdoutbuf, "\t%s\n", er)
 	}
 }
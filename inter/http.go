@@ -3,11 +3,19 @@ package inter
 import (
 	"bufio"
 	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
+	"fmt"
 	"io"
 	"log"
+	"math/rand/v2"
 	"net/http"
+	"net/url"
 	"os"
+	"strconv"
+	"sync"
 	"time"
 
 	"github.com/cloakwiss/ntdocs/utils"
@@ -18,20 +26,274 @@ var (
 	ErrHttpResponseReadingFailed = errors.New("Cannot read the response for GET request.")
 )
 
-func httpClient(url string) (*bufio.Reader, error) {
-	client := new(http.Client)
-	resp, er := client.Get(url)
+// Response with status other than 2xx, the body of such response is never returned
+type StatusError struct {
+	Url        string
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s responded with %d %s", e.Url, e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+// Statuses which may go away on their own, others fail at once
+func retryable(statusCode int) bool {
+	return statusCode == http.StatusTooManyRequests || statusCode == http.StatusRequestTimeout || statusCode >= 500
+}
+
+type FetcherConfig struct {
+	// Requests per second to a host and the number of requests which can be made at once after being idle
+	Rate  float64
+	Burst int
+	// Pages fetched at the same time by ReqWorkers
+	Workers int
+	// Limit for a single request including reading its body
+	Timeout time.Duration
+	// Retries after the first attempt, the delay before nth retry is random in [0, min(MaxBackoff, BaseBackoff*2^n)]
+	MaxRetries              int
+	BaseBackoff, MaxBackoff time.Duration
+}
+
+// Close to the old pacing of 4 workers starting a request every 3 seconds
+var DefaultFetcherConfig = FetcherConfig{
+	Rate:        0.5,
+	Burst:       2,
+	Workers:     4,
+	Timeout:     30 * time.Second,
+	MaxRetries:  4,
+	BaseBackoff: 2 * time.Second,
+	MaxBackoff:  time.Minute,
+}
+
+// HTTP client shared by the workers, requests to each host are limited by its own token bucket
+type Fetcher struct {
+	config  FetcherConfig
+	client  *http.Client
+	mutex   sync.Mutex
+	buckets map[string]*tokenBucket
+}
+
+func NewFetcher(config FetcherConfig) *Fetcher {
+	if config.Rate <= 0 {
+		config.Rate = DefaultFetcherConfig.Rate
+	}
+	if config.Burst < 1 {
+		config.Burst = 1
+	}
+	if config.Workers < 1 {
+		config.Workers = 1
+	}
+	return &Fetcher{
+		config:  config,
+		client:  &http.Client{Timeout: config.Timeout},
+		buckets: make(map[string]*tokenBucket),
+	}
+}
+
+func (f *Fetcher) bucket(host string) *tokenBucket {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	bucket, found := f.buckets[host]
+	if !found {
+		bucket = newTokenBucket(f.config.Rate, f.config.Burst)
+		f.buckets[host] = bucket
+	}
+	return bucket
+}
+
+// Validators of the stored page, when given the request is conditional and the page may be not modified
+type Validators struct {
+	ETag, LastModified string
+}
+
+type Page struct {
+	Url       string
+	Body      []byte
+	FetchedAt time.Time
+	// Validators sent by server for the next conditional request
+	Validators
+	// Body is empty when server responded with 304
+	NotModified bool
+}
+
+// Body of the url, retried with backoff on network errors and on 429, 408 and 5xx responses.
+// Retry-After of the response is used instead of the backoff when present, it also holds the other
+// requests to the same host.
+func (f *Fetcher) Get(ctx context.Context, rawUrl string) ([]byte, error) {
+	page, er := f.Fetch(ctx, rawUrl, Validators{})
+	return page.Body, er
+}
+
+// Same as Get, but the request is conditional on the validators and 304 is not a failure.
+// Waiting for the host and the request itself stop when ctx is done, the error wraps ctx.Err() then.
+func (f *Fetcher) Fetch(ctx context.Context, rawUrl string, validators Validators) (Page, error) {
+	parsed, er := url.Parse(rawUrl)
+	if er != nil {
+		return Page{}, fmt.Errorf("%w: %s: %v", ErrHttpGetRequestFailed, rawUrl, er)
+	}
+	bucket := f.bucket(parsed.Host)
+
+	for attempt := 0; ; attempt++ {
+		if er := bucket.wait(ctx); er != nil {
+			return Page{}, er
+		}
+		page, retryAfter, er := f.get(ctx, rawUrl, validators)
+		if er == nil {
+			return page, nil
+		}
+		var statusError *StatusError
+		if errors.As(er, &statusError) && !retryable(statusError.StatusCode) || ctx.Err() != nil {
+			return Page{}, er
+		}
+		if attempt >= f.config.MaxRetries {
+			return Page{}, fmt.Errorf("giving up after %d attempts: %w", attempt+1, er)
+		}
+
+		delay := f.backoff(attempt)
+		if retryAfter > 0 {
+			delay = min(retryAfter, f.config.MaxBackoff)
+			bucket.holdFor(delay)
+		}
+		if er := sleep(ctx, delay); er != nil {
+			return Page{}, er
+		}
+	}
+}
+
+func (f *Fetcher) get(ctx context.Context, rawUrl string, validators Validators) (page Page, retryAfter time.Duration, er error) {
+	request, er := http.NewRequestWithContext(ctx, http.MethodGet, rawUrl, nil)
+	if er != nil {
+		return page, 0, fmt.Errorf("%w: %s: %v", ErrHttpGetRequestFailed, rawUrl, er)
+	}
+	if validators.ETag != "" {
+		request.Header.Set("If-None-Match", validators.ETag)
+	}
+	if validators.LastModified != "" {
+		request.Header.Set("If-Modified-Since", validators.LastModified)
+	}
+	resp, er := f.client.Do(request)
 	if er != nil {
-		return nil, ErrHttpGetRequestFailed
+		if ctx.Err() != nil {
+			return page, 0, fmt.Errorf("%w: %s: %w", ErrHttpGetRequestFailed, rawUrl, ctx.Err())
+		}
+		return page, 0, fmt.Errorf("%w: %s: %v", ErrHttpGetRequestFailed, rawUrl, er)
 	}
 	defer resp.Body.Close()
-	buffer, er := io.ReadAll(resp.Body)
+
+	page = Page{
+		Url:        rawUrl,
+		FetchedAt:  time.Now().UTC(),
+		Validators: Validators{ETag: resp.Header.Get("ETag"), LastModified: resp.Header.Get("Last-Modified")},
+	}
+	if resp.StatusCode == http.StatusNotModified {
+		page.NotModified = true
+		// Server may leave out the validators in 304, the stored ones are still valid then
+		if page.ETag == "" && page.LastModified == "" {
+			page.Validators = validators
+		}
+		return page, 0, nil
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		io.Copy(io.Discard, resp.Body)
+		return Page{}, parseRetryAfter(resp.Header.Get("Retry-After")), &StatusError{Url: rawUrl, StatusCode: resp.StatusCode}
+	}
+	page.Body, er = io.ReadAll(resp.Body)
 	if er != nil {
-		return nil, ErrHttpResponseReadingFailed
+		return Page{}, 0, fmt.Errorf("%w: %s: %v", ErrHttpResponseReadingFailed, rawUrl, er)
+	}
+	return page, 0, nil
+}
+
+// Full jitter, so workers failing together do not retry together
+func (f *Fetcher) backoff(attempt int) time.Duration {
+	limit := f.config.BaseBackoff
+	for i := 0; i < attempt && limit < f.config.MaxBackoff; i++ {
+		limit *= 2
+	}
+	limit = min(limit, f.config.MaxBackoff)
+	if limit <= 0 {
+		return 0
+	}
+	return rand.N(limit + 1)
+}
+
+// Retry-After is either seconds or an HTTP date, 0 when it is absent or cannot be read
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, er := strconv.Atoi(value); er == nil {
+		return max(time.Duration(seconds)*time.Second, 0)
+	}
+	if date, er := http.ParseTime(value); er == nil {
+		return max(time.Until(date), 0)
+	}
+	return 0
+}
+
+// Token bucket where waiting takes the token in advance, so waiters are served in the order they came
+type tokenBucket struct {
+	mutex  sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	return &tokenBucket{rate: rate, burst: float64(burst), tokens: float64(burst), last: time.Now()}
+}
+
+func (b *tokenBucket) refill(now time.Time) {
+	if now.After(b.last) {
+		b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+		b.last = now
+	}
+}
+
+// Token taken by a wait which is cancelled is given back
+func (b *tokenBucket) wait(ctx context.Context) error {
+	b.mutex.Lock()
+	now := time.Now()
+	b.refill(now)
+	b.tokens--
+	var delay time.Duration
+	if b.tokens < 0 {
+		delay = time.Duration(-b.tokens / b.rate * float64(time.Second))
+	}
+	b.mutex.Unlock()
+	if er := sleep(ctx, delay); er != nil {
+		b.mutex.Lock()
+		b.tokens++
+		b.mutex.Unlock()
+		return er
+	}
+	return nil
+}
+
+// time.Sleep which stops when ctx is done
+func sleep(ctx context.Context, delay time.Duration) error {
+	if delay <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// No token is given out for the duration, used when the host asks to wait with Retry-After
+func (b *tokenBucket) holdFor(delay time.Duration) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.refill(time.Now())
+	if owed := -delay.Seconds() * b.rate; owed < b.tokens {
+		b.tokens = owed
 	}
-	// fmt.Println(string(buffer))
-	reader := bytes.NewReader(buffer)
-	return bufio.NewReader(reader), nil
 }
 
 var (
@@ -40,50 +302,102 @@ var (
 	UWhite   = "\033[4;37m" // White
 )
 
-func ReqWorkers(symbols []SymbolRecord, forCompressed chan<- RawHTMLRecord) {
+// Page to be fetched by FetchPages, stored as Name in RawHTML
+type PageRequest struct {
+	Name, Url string
+	Validators
+}
+
+// Fetches the pages of symbols with the workers of fetcher, pages which cannot be fetched are sent with Err
+func ReqWorkers(ctx context.Context, fetcher *Fetcher, symbols []SymbolRecord, forCompressed chan<- RawHTMLRecord) {
+	requests := make([]PageRequest, 0, len(symbols))
+	for _, symbol := range symbols {
+		requests = append(requests, PageRequest{Name: symbol.Name, Url: symbol.ScrapableUrl()})
+	}
+	FetchPages(ctx, fetcher, requests, forCompressed)
+}
+
+// Same as ReqWorkers for the requests which may be conditional, pages not modified are sent without HtmlBlob.
+// Requests left when ctx is done are not sent at all.
+func FetchPages(ctx context.Context, fetcher *Fetcher, requests []PageRequest, forCompressed chan<- RawHTMLRecord) {
+	jobs := make(chan PageRequest)
+	go func() {
+		defer close(jobs)
+		for _, request := range requests {
+			select {
+			case jobs <- request:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	FetchWorkers(ctx, fetcher, jobs, forCompressed)
+}
+
+// Workers of fetcher fetching the requests till jobs is closed, forCompressed is closed after the last one is sent.
+// Every request gets a record, the ones which are stopped by ctx have Err wrapping ctx.Err().
+func FetchWorkers(ctx context.Context, fetcher *Fetcher, jobs <-chan PageRequest, forCompressed chan<- RawHTMLRecord) {
 	var (
-		limit          = 4
-		logger         = log.New(os.Stdout, "Request Worker ", log.Ltime)
-		workersCounter = make(chan bool, limit)
-		l              = len(symbols)
+		logger  = log.New(os.Stdout, "Request Worker ", log.Ltime)
+		waiter  sync.WaitGroup
+		counter sync.Mutex
+		done    = 0
 	)
-	for idx := range l {
-		workersCounter <- true
-		go func(name string, url string, i int) {
-			buf := work(logger, url)
-			logger.Printf("\tSymbols Left: %s%d%s,\tScraped:  %s%s%s\n", BWhite, l-i-1, ColorOff, UWhite, name, ColorOff)
-			forCompressed <- RawHTMLRecord{name, buf}
-			<-workersCounter
-		}(symbols[idx].Name, symbols[idx].ScrapableUrl(), idx)
-		time.Sleep(3 * time.Second)
-		idx += 1
+	for range fetcher.config.Workers {
+		waiter.Add(1)
+		go func() {
+			defer waiter.Done()
+			for request := range jobs {
+				record := work(ctx, fetcher, logger, request)
+				counter.Lock()
+				done++
+				logger.Printf("\tSymbols Done: %s%d%s,\tScraped:  %s%s%s\n", BWhite, done, ColorOff, UWhite, request.Name, ColorOff)
+				counter.Unlock()
+				forCompressed <- record
+			}
+		}()
 	}
 
 	// for waiting
-	for range limit {
-		workersCounter <- true
-	}
+	waiter.Wait()
 	close(forCompressed)
 }
 
-func work(logger *log.Logger, url string) []byte {
-	response, err := httpClient(url)
+func work(ctx context.Context, fetcher *Fetcher, logger *log.Logger, request PageRequest) RawHTMLRecord {
+	failed := func(er error) RawHTMLRecord {
+		logger.Printf("ERROR : %s : %s", er.Error(), request.Url)
+		return RawHTMLRecord{SymbolName: request.Name, Url: request.Url, Err: er}
+	}
+	page, err := fetcher.Fetch(ctx, request.Url, request.Validators)
+	if err != nil {
+		return failed(err)
+	}
+	// Url on the documentation is stored, so RawHTML stays same whichever source it was fetched from
+	if path, internal := utils.ResolveLink("/", page.Url); internal {
+		page.Url = utils.DocumentationUrl(path)
+	}
+	record := RawHTMLRecord{
+		SymbolName:  request.Name,
+		Url:         page.Url,
+		FetchedAt:   page.FetchedAt,
+		Validators:  page.Validators,
+		NotModified: page.NotModified,
+	}
+	if page.NotModified {
+		return record
+	}
 	// ALERT
-	response = utils.SelectMainContent(response)
+	response := utils.SelectMainContent(bufio.NewReader(bytes.NewReader(page.Body)))
 	// ALERT
-	if err == nil {
-		buf, er := GetCompressed(response)
-		if er != nil {
-			logger.Printf("ERROR : %s : %s", er.Error(), url)
-			return nil
-		}
-		return buf
-	} else {
-		if errors.Is(err, ErrHttpGetRequestFailed) {
-			logger.Printf("ERROR : %s => %s", err.Error(), url)
-		} else if errors.Is(err, ErrHttpResponseReadingFailed) {
-			logger.Printf("ERROR : %s => %s", err.Error(), url)
-		}
-		return nil
+	main, er := io.ReadAll(response)
+	if er != nil {
+		return failed(er)
+	}
+	hash := sha256.Sum256(main)
+	record.ContentHash = hex.EncodeToString(hash[:])
+	record.HtmlBlob, er = GetCompressed(bufio.NewReader(bytes.NewReader(main)))
+	if er != nil {
+		return failed(er)
 	}
+	return record
 }
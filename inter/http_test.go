@@ -0,0 +1,119 @@
+package inter_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cloakwiss/ntdocs/inter"
+)
+
+func testFetcher() *inter.Fetcher {
+	return inter.NewFetcher(inter.FetcherConfig{
+		Rate:        1000,
+		Burst:       1,
+		Workers:     1,
+		Timeout:     100 * time.Millisecond,
+		MaxRetries:  2,
+		BaseBackoff: time.Millisecond,
+		MaxBackoff:  10 * time.Millisecond,
+	})
+}
+
+func TestFetcherRetry(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		switch attempts {
+		case 1:
+			w.Header().Set("Retry-After", "120")
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte("Too many requests"))
+		case 2:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			w.Write([]byte("docs"))
+		}
+	}))
+	defer server.Close()
+
+	body, er := testFetcher().Get(context.Background(), server.URL)
+	if er != nil || string(body) != "docs" || attempts != 3 {
+		t.Errorf("Got %q, %v after %d attempts", body, er, attempts)
+	}
+}
+
+func TestFetcherFailure(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		switch r.URL.Path {
+		case "/missing":
+			http.NotFound(w, r)
+		case "/slow":
+			time.Sleep(200 * time.Millisecond)
+		default:
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer server.Close()
+	fetcher := testFetcher()
+
+	var statusError *inter.StatusError
+	if body, er := fetcher.Get(context.Background(), server.URL+"/missing"); !errors.As(er, &statusError) || statusError.StatusCode != http.StatusNotFound || body != nil || attempts != 1 {
+		t.Errorf("404 gave %q, %v after %d attempts", body, er, attempts)
+	}
+
+	attempts = 0
+	if _, er := fetcher.Get(context.Background(), server.URL+"/failing"); !errors.As(er, &statusError) || statusError.StatusCode != http.StatusBadGateway || attempts != 3 {
+		t.Errorf("502 gave %v after %d attempts", er, attempts)
+	}
+
+	if _, er := fetcher.Get(context.Background(), server.URL+"/slow"); !errors.Is(er, inter.ErrHttpGetRequestFailed) {
+		t.Errorf("Slow response gave %v", er)
+	}
+}
+
+func TestFetcherConditional(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Last-Modified", "Tue, 13 Oct 2026 10:00:00 GMT")
+		w.Write([]byte("docs"))
+	}))
+	defer server.Close()
+	fetcher := testFetcher()
+
+	page, er := fetcher.Fetch(context.Background(), server.URL, inter.Validators{})
+	if er != nil || page.NotModified || string(page.Body) != "docs" || page.ETag != `"v1"` || page.LastModified == "" {
+		t.Fatalf("First fetch gave %+v, %v", page, er)
+	}
+	again, er := fetcher.Fetch(context.Background(), server.URL, page.Validators)
+	if er != nil || !again.NotModified || again.Body != nil || again.Validators != page.Validators {
+		t.Errorf("Conditional fetch gave %+v, %v", again, er)
+	}
+}
+
+func TestFetcherCancel(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+	fetcher := inter.NewFetcher(inter.FetcherConfig{Rate: 1000, Workers: 1, MaxRetries: 10, BaseBackoff: time.Hour, MaxBackoff: time.Hour})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, er := fetcher.Get(ctx, server.URL); !errors.Is(er, context.DeadlineExceeded) {
+		t.Errorf("Cancelled fetch gave %v", er)
+	}
+	if waited := time.Since(start); waited > time.Second {
+		t.Errorf("Cancelled fetch waited %v", waited)
+	}
+}
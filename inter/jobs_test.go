@@ -0,0 +1,138 @@
+package inter_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/cloakwiss/ntdocs/inter"
+)
+
+type scrapeJob struct {
+	state    string
+	attempts int
+	cause    sql.NullString
+}
+
+func readJob(t *testing.T, db *sql.DB, name string) (job scrapeJob) {
+	t.Helper()
+	er := db.QueryRow(`SELECT state, attempts, error FROM ScrapeJobs WHERE symbol_name = ?;`, name).Scan(&job.state, &job.attempts, &job.cause)
+	if er != nil {
+		t.Fatal(er)
+	}
+	return
+}
+
+func claim(t *testing.T, db *sql.DB, expected string) {
+	t.Helper()
+	request, ok, er := inter.ClaimScrapeJob(context.Background(), db)
+	if er != nil {
+		t.Fatal(er)
+	}
+	if !ok || request.Name != expected {
+		t.Fatalf("Expected to claim %s, found %q %v", expected, request.Name, ok)
+	}
+}
+
+func TestScrapeJobs(t *testing.T) {
+	ctx := context.Background()
+	db, er := sql.Open("sqlite3", filepath.Join(t.TempDir(), "ntdocs.db"))
+	if er != nil {
+		t.Fatal(er)
+	}
+	defer db.Close()
+	if _, er := db.Exec(`CREATE TABLE RawHTML (symbolName TEXT NOT NULL, html BLOB NOT NULL);`); er != nil {
+		t.Fatal(er)
+	}
+
+	records := []inter.SymbolRecord{
+		{Header: "memoryapi.h", Name: "A", Ttype: "function", Url: "/windows/win32/api/memoryapi/nf-memoryapi-a"},
+		{Header: "memoryapi.h", Name: "B", Ttype: "function", Url: "/windows/win32/api/memoryapi/nf-memoryapi-b"},
+		{Header: "memoryapi.h", Name: "C", Ttype: "function", Url: "/windows/win32/api/memoryapi/nf-memoryapi-c"},
+	}
+	if er := inter.EnqueueScrapeJobs(ctx, db, records); er != nil {
+		t.Fatal(er)
+	}
+
+	claim(t, db, "A")
+	if job := readJob(t, db, "A"); job.state != "in-flight" || job.attempts != 1 {
+		t.Errorf("Claimed job is %+v", job)
+	}
+	er = inter.CompleteScrapeJob(ctx, db, inter.RawHTMLRecord{SymbolName: "A", HtmlBlob: []byte("page"), Url: records[0].Url})
+	if er != nil {
+		t.Fatal(er)
+	}
+	if job := readJob(t, db, "A"); job.state != "done" || job.cause.Valid {
+		t.Errorf("Completed job is %+v", job)
+	}
+	var stored int
+	if er := db.QueryRow(`SELECT count(*) FROM RawHTML WHERE symbolName = 'A';`).Scan(&stored); er != nil || stored != 1 {
+		t.Errorf("Page is stored %d times: %v", stored, er)
+	}
+
+	claim(t, db, "B")
+	if er := inter.CompleteScrapeJob(ctx, db, inter.RawHTMLRecord{SymbolName: "B", Err: errors.New("404 Not Found")}); er != nil {
+		t.Fatal(er)
+	}
+	if job := readJob(t, db, "B"); job.state != "failed" || job.attempts != 1 || job.cause.String != "404 Not Found" {
+		t.Errorf("Failed job is %+v", job)
+	}
+
+	// Stopped jobs are pending again and the attempt is not counted
+	claim(t, db, "C")
+	if er := inter.CompleteScrapeJob(ctx, db, inter.RawHTMLRecord{SymbolName: "C", Err: context.Canceled}); er != nil {
+		t.Fatal(er)
+	}
+	if job := readJob(t, db, "C"); job.state != "pending" || job.attempts != 0 {
+		t.Errorf("Cancelled job is %+v", job)
+	}
+	claim(t, db, "C")
+	if er := inter.ReleaseScrapeJob(ctx, db, "C"); er != nil {
+		t.Fatal(er)
+	}
+	if job := readJob(t, db, "C"); job.state != "pending" || job.attempts != 0 {
+		t.Errorf("Released job is %+v", job)
+	}
+
+	// Job left in-flight by a crash, resume takes only it and retry-failed the failed ones too
+	claim(t, db, "C")
+	if reset, er := inter.ResetScrapeJobs(ctx, db, "in-flight"); er != nil || reset != 1 {
+		t.Errorf("Resume reset %d jobs: %v", reset, er)
+	}
+	if reset, er := inter.ResetScrapeJobs(ctx, db, "in-flight", "failed"); er != nil || reset != 1 {
+		t.Errorf("Retry reset %d jobs: %v", reset, er)
+	}
+	for _, name := range []string{"B", "C"} {
+		if job := readJob(t, db, name); job.state != "pending" || job.attempts != 1 {
+			t.Errorf("Reset job %s is %+v", name, job)
+		}
+	}
+
+	// Done job is scraped again when it is enqueued again
+	if er := inter.EnqueueScrapeJobs(ctx, db, records[:1]); er != nil {
+		t.Fatal(er)
+	}
+	if job := readJob(t, db, "A"); job.state != "pending" || job.cause.Valid {
+		t.Errorf("Enqueued job is %+v", job)
+	}
+
+	counts, er := inter.ScrapeJobCounts(db)
+	if er != nil {
+		t.Fatal(er)
+	}
+	if counts["pending"] != 3 || len(counts) != 1 {
+		t.Errorf("Wrong counts: %v", counts)
+	}
+	for range 3 {
+		if _, ok, er := inter.ClaimScrapeJob(ctx, db); !ok || er != nil {
+			t.Fatalf("Pending job is not claimed: %v", er)
+		}
+	}
+	if _, ok, er := inter.ClaimScrapeJob(ctx, db); ok || er != nil {
+		t.Errorf("Claimed from an empty queue: %v %v", ok, er)
+	}
+}
@@ -0,0 +1,417 @@
+// This file contains the schema of the tables which are created by this tool, tables like RawHTML were expected
+// to be present before hand and are created only for a new database
+package inter
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/cloakwiss/ntdocs/utils"
+)
+
+// Tables of the databases made before this tool created its tables, with only their first columns as the rest are
+// added where they are filled. Created by OpenDB, so a new database i.e. one scraped from fixtures works too.
+const baseSchema string = `
+	CREATE TABLE IF NOT EXISTS RawHTML (
+		symbolName TEXT NOT NULL,
+		html       BLOB NOT NULL
+	);
+	CREATE TABLE IF NOT EXISTS FunctionSymbols (
+		name         TEXT PRIMARY KEY,
+		arity        INTEGER,
+		return       TEXT,
+		description  TEXT,
+		requirements TEXT
+	);
+	CREATE TABLE IF NOT EXISTS FunctionParameters (
+		function_name TEXT NOT NULL,
+		srno          INTEGER NOT NULL,
+		name          TEXT,
+		datatype      TEXT,
+		usage         TEXT,
+		documentation TEXT
+	);
+	CREATE TABLE IF NOT EXISTS StructureSymbols (
+		name         TEXT NOT NULL,
+		member_count INTEGER,
+		description  TEXT,
+		requirement  TEXT
+	);
+	CREATE TABLE IF NOT EXISTS StructureMembers (
+		structure_name TEXT NOT NULL,
+		srno           INTEGER NOT NULL,
+		datatype       TEXT,
+		name           TEXT
+	);
+	CREATE TABLE IF NOT EXISTS StructurePointer (
+		pointer_name   TEXT NOT NULL,
+		structure_name TEXT NOT NULL
+	);`
+
+// Headers keeps the toc.json of each header as fetched, Symbol is rebuilt from it by the discovery.
+// Databases made before the discovery already have these tables, so nothing more is constrained here.
+const headerSchema string = `
+	CREATE TABLE IF NOT EXISTS Headers (
+		name      TEXT PRIMARY KEY,
+		json_blob BLOB NOT NULL
+	);
+	CREATE TABLE IF NOT EXISTS Symbol (
+		header TEXT NOT NULL,
+		name   TEXT NOT NULL,
+		type   TEXT,
+		url    TEXT
+	);`
+
+const enumerationSchema string = `
+	CREATE TABLE IF NOT EXISTS EnumSymbols (
+		name           TEXT PRIMARY KEY,
+		tag            TEXT NULL,
+		constant_count INTEGER NOT NULL,
+		description    TEXT
+	);
+	CREATE TABLE IF NOT EXISTS EnumConstants (
+		enum_name     TEXT NOT NULL REFERENCES EnumSymbols(name),
+		srno          INTEGER NOT NULL,
+		name          TEXT NOT NULL,
+		value         INTEGER NULL,
+		expression    TEXT NULL,
+		documentation TEXT,
+		PRIMARY KEY (enum_name, srno)
+	);`
+
+const callbackSchema string = `
+	CREATE TABLE IF NOT EXISTS CallbackSymbols (
+		name               TEXT PRIMARY KEY,
+		function_name      TEXT NOT NULL,
+		calling_convention TEXT NULL,
+		is_pointer         BOOLEAN NOT NULL DEFAULT 1,
+		arity              INTEGER NOT NULL,
+		return             TEXT NOT NULL,
+		description        TEXT,
+		requirements       TEXT
+	);
+	CREATE TABLE IF NOT EXISTS CallbackParameters (
+		callback_name TEXT NOT NULL REFERENCES CallbackSymbols(name),
+		srno          INTEGER NOT NULL,
+		name          TEXT,
+		datatype      TEXT NOT NULL,
+		usage         TEXT,
+		documentation TEXT,
+		PRIMARY KEY (callback_name, srno)
+	);`
+
+// Complete layout of the structure, StructureMembers only contain the top level members.
+// Row with id 0 is the structure itself, nested aggregates are rebuilt by following parent_id.
+const structureFieldSchema string = `
+	CREATE TABLE IF NOT EXISTS StructureFields (
+		structure_name TEXT NOT NULL,
+		id             INTEGER NOT NULL,
+		parent_id      INTEGER NULL,
+		srno           INTEGER NOT NULL,
+		kind           TEXT CHECK(kind IN ('struct', 'union', 'field')) NOT NULL,
+		tag            TEXT NULL,
+		datatype       TEXT NULL,
+		name           TEXT NULL,
+		bit_width      TEXT NULL,
+		dimensions     TEXT NULL,
+		PRIMARY KEY (structure_name, id)
+	);`
+
+// Typed form of the requirements table, multi valued rows are kept in SymbolRequirementItems
+const requirementsSchema string = `
+	CREATE TABLE IF NOT EXISTS SymbolRequirements (
+		symbol_name      TEXT PRIMARY KEY,
+		header           TEXT NULL,
+		target_platform  TEXT NULL,
+		min_client       TEXT NULL,
+		min_client_major INTEGER NULL,
+		min_client_minor INTEGER NULL,
+		min_client_build INTEGER NULL,
+		min_server       TEXT NULL,
+		min_server_major INTEGER NULL,
+		min_server_minor INTEGER NULL,
+		min_server_build INTEGER NULL
+	);
+	CREATE TABLE IF NOT EXISTS SymbolRequirementItems (
+		symbol_name TEXT NOT NULL REFERENCES SymbolRequirements(symbol_name),
+		kind        TEXT CHECK(kind IN ('include', 'library', 'dll', 'api_set')) NOT NULL,
+		srno        INTEGER NOT NULL,
+		value       TEXT NOT NULL,
+		PRIMARY KEY (symbol_name, kind, srno)
+	);
+	CREATE INDEX IF NOT EXISTS SymbolRequirementItemsValue ON SymbolRequirementItems(kind, value COLLATE NOCASE);`
+
+// Flags and constants documented in `Value | Meaning` tables of parameters and members,
+// owner_kind with owner_name and member_name point to the parameter or member which accepts them
+const valueConstantSchema string = `
+	CREATE TABLE IF NOT EXISTS ValueConstants (
+		owner_kind  TEXT CHECK(owner_kind IN ('function', 'callback', 'structure')) NOT NULL,
+		owner_name  TEXT NOT NULL,
+		member_name TEXT NOT NULL,
+		srno        INTEGER NOT NULL,
+		name        TEXT NULL,
+		value       INTEGER NULL,
+		value_text  TEXT NULL,
+		description TEXT,
+		PRIMARY KEY (owner_kind, owner_name, member_name, srno)
+	);
+	CREATE INDEX IF NOT EXISTS ValueConstantsName ON ValueConstants(name);`
+
+// Sections of the page which are not parsed any further, and the links found in the documentation.
+// target is the name of the Symbol which was linked, it is NULL when the link goes outside of Symbol table
+const referenceSchema string = `
+	CREATE TABLE IF NOT EXISTS SymbolSections (
+		symbol_name TEXT NOT NULL,
+		section     TEXT NOT NULL,
+		content     TEXT,
+		PRIMARY KEY (symbol_name, section)
+	);
+	CREATE TABLE IF NOT EXISTS SymbolReferences (
+		source      TEXT NOT NULL,
+		section     TEXT NOT NULL,
+		srno        INTEGER NOT NULL,
+		text        TEXT,
+		href        TEXT NOT NULL,
+		target_path TEXT NULL,
+		target      TEXT NULL,
+		PRIMARY KEY (source, section, srno)
+	);
+	CREATE INDEX IF NOT EXISTS SymbolReferencesTarget ON SymbolReferences(target);`
+
+// Macros are kept apart from FunctionSymbols as they cannot be imported from any DLL.
+// MacroParameters are from the function form of the syntax, datatype is NULL when it is not written.
+const macroSchema string = `
+	CREATE TABLE IF NOT EXISTS MacroSymbols (
+		name                 TEXT PRIMARY KEY,
+		parameters           TEXT NULL,
+		body                 TEXT NULL,
+		return               TEXT NULL,
+		description          TEXT,
+		description_markdown TEXT NULL,
+		description_text     TEXT NULL,
+		requirements         TEXT
+	);
+	CREATE TABLE IF NOT EXISTS MacroParameters (
+		macro_name             TEXT NOT NULL REFERENCES MacroSymbols(name),
+		srno                   INTEGER NOT NULL,
+		name                   TEXT,
+		datatype               TEXT NULL,
+		usage                  TEXT,
+		documentation          TEXT,
+		documentation_markdown TEXT NULL,
+		documentation_text     TEXT NULL,
+		PRIMARY KEY (macro_name, srno)
+	);`
+
+// COM interfaces, InterfaceMethods are in order of the methods table of the page, which is alphabetical and not
+// the vtable order, base interface's methods are not repeated. Signature columns are filled once the page of method is scraped.
+const interfaceSchema string = `
+	CREATE TABLE IF NOT EXISTS InterfaceSymbols (
+		name                 TEXT PRIMARY KEY,
+		iid                  TEXT NULL,
+		base_interface       TEXT NULL,
+		method_count         INTEGER NOT NULL,
+		description          TEXT,
+		description_markdown TEXT NULL,
+		description_text     TEXT NULL,
+		requirements         TEXT
+	);
+	CREATE TABLE IF NOT EXISTS InterfaceMethods (
+		interface_name     TEXT NOT NULL REFERENCES InterfaceSymbols(name),
+		srno               INTEGER NOT NULL,
+		name               TEXT NOT NULL,
+		href               TEXT NULL,
+		target_path        TEXT NULL,
+		description        TEXT,
+		return             TEXT NULL,
+		arity              INTEGER NULL,
+		calling_convention TEXT NULL,
+		return_documentation TEXT NULL,
+		PRIMARY KEY (interface_name, srno)
+	);
+	CREATE TABLE IF NOT EXISTS InterfaceMethodParameters (
+		interface_name         TEXT NOT NULL,
+		method_name            TEXT NOT NULL,
+		srno                   INTEGER NOT NULL,
+		name                   TEXT,
+		datatype               TEXT NOT NULL,
+		usage                  TEXT,
+		documentation          TEXT,
+		direction              TEXT CHECK(direction IN ('in', 'out', 'inout')) NULL,
+		optional               BOOLEAN NOT NULL DEFAULT 0,
+		reserved               BOOLEAN NOT NULL DEFAULT 0,
+		size_parameter         TEXT NULL,
+		size_unit              TEXT CHECK(size_unit IN ('bytes', 'elements')) NULL,
+		documentation_markdown TEXT NULL,
+		documentation_text     TEXT NULL,
+		PRIMARY KEY (interface_name, method_name, srno)
+	);`
+
+// C++ classes of `nl-` pages. ClassMembers are the data members from the syntax, ClassMethods are in order of the
+// methods table of the page and an overloaded method has one row for each overload.
+const classSchema string = `
+	CREATE TABLE IF NOT EXISTS ClassSymbols (
+		name                 TEXT PRIMARY KEY,
+		base_classes         TEXT NULL,
+		member_count         INTEGER NOT NULL,
+		method_count         INTEGER NOT NULL,
+		description          TEXT,
+		description_markdown TEXT NULL,
+		description_text     TEXT NULL,
+		requirements         TEXT
+	);
+	CREATE TABLE IF NOT EXISTS ClassMembers (
+		class_name TEXT NOT NULL REFERENCES ClassSymbols(name),
+		srno       INTEGER NOT NULL,
+		name       TEXT NULL,
+		datatype   TEXT NOT NULL,
+		bit_width  TEXT NULL,
+		dimensions TEXT NULL,
+		PRIMARY KEY (class_name, srno)
+	);
+	CREATE TABLE IF NOT EXISTS ClassMethods (
+		class_name  TEXT NOT NULL REFERENCES ClassSymbols(name),
+		srno        INTEGER NOT NULL,
+		name        TEXT NOT NULL,
+		overload    TEXT NULL,
+		kind        TEXT CHECK(kind IN ('method', 'constructor', 'destructor', 'operator')) NOT NULL,
+		href        TEXT NULL,
+		target_path TEXT NULL,
+		description TEXT,
+		PRIMARY KEY (class_name, srno)
+	);`
+
+// Queue of the pages to scrape, a job is in-flight while its page is being fetched and failed jobs keep the error
+const scrapeJobSchema string = `
+	CREATE TABLE IF NOT EXISTS ScrapeJobs (
+		symbol_name TEXT PRIMARY KEY,
+		url         TEXT NOT NULL,
+		state       TEXT CHECK(state IN ('pending', 'in-flight', 'done', 'failed')) NOT NULL DEFAULT 'pending',
+		error       TEXT NULL,
+		attempts    INTEGER NOT NULL DEFAULT 0,
+		updated_at  TEXT NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS ScrapeJobsState ON ScrapeJobs(state);`
+
+// Pages which changed since they were filled, the records parsed from them are dropped before the next fill
+const staleSchema string = `
+	CREATE TABLE IF NOT EXISTS StaleSymbols (
+		symbol_name TEXT PRIMARY KEY,
+		changed_at  TEXT NOT NULL
+	);`
+
+// Where and when the page was fetched and the hash of its main content, added to RawHTML
+var rawHTMLColumns = utils.AssociativeArray[string, string]{
+	{Key: "url", Value: "TEXT NULL"},
+	{Key: "fetched_at", Value: "TEXT NULL"},
+	{Key: "etag", Value: "TEXT NULL"},
+	{Key: "last_modified", Value: "TEXT NULL"},
+	{Key: "content_hash", Value: "TEXT NULL"},
+}
+
+// Url of the toc.json, the relative links in it are resolved against this. Added to Headers
+var headerColumns = utils.AssociativeArray[string, string]{
+	{Key: "url", Value: "TEXT NULL"},
+}
+
+// Return value section of the function, added to FunctionSymbols
+var returnValueColumns = utils.AssociativeArray[string, string]{
+	{Key: "return_documentation", Value: "TEXT NULL"},
+	{Key: "error_convention", Value: "TEXT NULL"},
+	{Key: "success_value", Value: "TEXT NULL"},
+	{Key: "failure_value", Value: "TEXT NULL"},
+	{Key: "sets_last_error", Value: "BOOLEAN NOT NULL DEFAULT 0"},
+}
+
+// Markdown and plain text forms of description, added to FunctionSymbols and CallbackSymbols
+var renderedDescriptionColumns = utils.AssociativeArray[string, string]{
+	{Key: "description_markdown", Value: "TEXT NULL"},
+	{Key: "description_text", Value: "TEXT NULL"},
+}
+
+// Markdown and plain text forms of documentation, added to FunctionParameters and CallbackParameters
+var renderedDocumentationColumns = utils.AssociativeArray[string, string]{
+	{Key: "documentation_markdown", Value: "TEXT NULL"},
+	{Key: "documentation_text", Value: "TEXT NULL"},
+}
+
+// Documentation of the member from members section of the page, added to StructureMembers
+var memberDocumentationColumns = utils.AssociativeArray[string, string]{
+	{Key: "documentation", Value: "TEXT NULL"},
+	{Key: "documentation_markdown", Value: "TEXT NULL"},
+	{Key: "documentation_text", Value: "TEXT NULL"},
+}
+
+// Normalized form of usage hint, added to FunctionParameters and CallbackParameters
+var usageColumns = utils.AssociativeArray[string, string]{
+	{Key: "direction", Value: "TEXT CHECK(direction IN ('in', 'out', 'inout')) NULL"},
+	{Key: "optional", Value: "BOOLEAN NOT NULL DEFAULT 0"},
+	{Key: "reserved", Value: "BOOLEAN NOT NULL DEFAULT 0"},
+	{Key: "size_parameter", Value: "TEXT NULL"},
+	{Key: "size_unit", Value: "TEXT CHECK(size_unit IN ('bytes', 'elements')) NULL"},
+}
+
+// Columns read by ntquery from the tables present before hand. OpenDB adds them up front, as a database not filled
+// again by this tool would not have them otherwise.
+var openColumns = []struct {
+	table   string
+	columns utils.AssociativeArray[string, string]
+}{
+	{"FunctionParameters", usageColumns},
+	{"CallbackParameters", usageColumns},
+	{"FunctionSymbols", returnValueColumns},
+	{"FunctionSymbols", renderedDescriptionColumns},
+	{"CallbackSymbols", renderedDescriptionColumns},
+	{"FunctionParameters", renderedDocumentationColumns},
+	{"CallbackParameters", renderedDocumentationColumns},
+}
+
+// Adds openColumns to the tables which exist, the rest get them when they are created and filled
+func addOpenColumns(conn *sql.DB) error {
+	for _, open := range openColumns {
+		if !tableExists(conn, open.table) {
+			continue
+		}
+		if er := addColumns(conn, open.table, open.columns); er != nil {
+			return er
+		}
+	}
+	return nil
+}
+
+func createTables(conn *sql.DB, schema string) error {
+	if _, er := conn.Exec(schema); er != nil {
+		return fmt.Errorf("cannot create the tables: %w", er)
+	}
+	return nil
+}
+
+// Adds the columns which are not already present in table, so this can be called on every insertion
+func addColumns(conn *sql.DB, table string, columns utils.AssociativeArray[string, string]) error {
+	rows, er := conn.Query(fmt.Sprintf("SELECT name FROM pragma_table_info('%s');", table))
+	if er != nil {
+		return fmt.Errorf("cannot read columns of %s: %w", table, er)
+	}
+	present := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		if er := rows.Scan(&name); er != nil {
+			rows.Close()
+			return fmt.Errorf("cannot read columns of %s: %w", table, er)
+		}
+		present[name] = true
+	}
+	if er := rows.Close(); er != nil {
+		return er
+	}
+
+	for _, column := range columns {
+		if present[column.Key] {
+			continue
+		}
+		if _, er := conn.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s;", table, column.Key, column.Value)); er != nil {
+			return fmt.Errorf("cannot add column %s to %s: %w", column.Key, table, er)
+		}
+	}
+	return nil
+}
@@ -4,22 +4,51 @@ package inter
 
 import (
 	"bufio"
+	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io/fs"
 	"log"
+	"os"
 	"strings"
+	"time"
 
+	"github.com/PuerkitoBio/goquery"
+
+	"github.com/cloakwiss/ntdocs/symbols/callback"
+	"github.com/cloakwiss/ntdocs/symbols/class"
+	"github.com/cloakwiss/ntdocs/symbols/cominterface"
+	"github.com/cloakwiss/ntdocs/symbols/enumeration"
 	"github.com/cloakwiss/ntdocs/symbols/function"
+	"github.com/cloakwiss/ntdocs/symbols/macro"
 	"github.com/cloakwiss/ntdocs/symbols/structure"
+	"github.com/cloakwiss/ntdocs/utils"
 	"github.com/k0kubun/pp/v3"
 	_ "github.com/mattn/go-sqlite3"
 )
 
 func OpenDB() (*sql.DB, func() error) {
+	info, er := os.Stat("./ntdocs.db")
+	isNew := errors.Is(er, fs.ErrNotExist) || (er == nil && info.Size() == 0)
 	db, err := sql.Open("sqlite3", "./ntdocs.db")
 	if err != nil {
 		log.Panicf("Cannot open ntdocs.db : %s\n", err)
 	}
+	// Records are inserted while the pages are still being read, which needs WAL. It persists in the file, so it is
+	// set only for a new database and the existing ones keep their journal mode.
+	if isNew {
+		if _, er := db.Exec("PRAGMA journal_mode = WAL;"); er != nil {
+			log.Panicf("Cannot set journal mode of ntdocs.db : %s\n", er)
+		}
+	}
+	if er := createTables(db, baseSchema); er != nil {
+		log.Panicln(er)
+	}
+	if er := addOpenColumns(db); er != nil {
+		log.Panicln(er)
+	}
 	return db, db.Close
 }
 
@@ -54,23 +83,55 @@ type SymbolRecord struct {
 	Header, Name, Ttype, Url string
 }
 
+// Url the page is fetched from, see utils.SetSourceUrl
 func (sym *SymbolRecord) ScrapableUrl() string {
-	return fmt.Sprintf("https://learn.microsoft.com/en-us%s", sym.Url)
+	return utils.SourceUrl(sym.Url)
 }
 
 type RawHTMLRecord struct {
 	SymbolName string
 	HtmlBlob   []byte
+	// Where and when the page was fetched, with the validators for refreshing it
+	Url       string
+	FetchedAt time.Time
+	Validators
+	// Hash of main content before compression, so changes in the rest of the page are ignored
+	ContentHash string
+	// Only set while refreshing, HtmlBlob is empty then
+	NotModified bool
+	// Set when the page could not be fetched, such record is never stored
+	Err error
 }
 
 func AddToRawHTML(conn *sql.DB, rec RawHTMLRecord) {
-	stmt, er := conn.Prepare("INSERT INTO RawHTML (symbolName, html) VALUES (?, ?);")
-	if er != nil {
-		log.Panic("Failed to prepare the insert statement")
+	if er := addColumns(conn, "RawHTML", rawHTMLColumns); er != nil {
+		log.Panicln(er)
+	}
+	if er := insertRawHTML(conn, rec); er != nil {
+		log.Panicln(er)
 	}
-	if _, er := stmt.Exec(rec.SymbolName, rec.HtmlBlob); er != nil {
-		log.Panic("Insert failed")
+}
+
+// Common to the connection and transaction
+type executor interface {
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
+func insertRawHTML(conn executor, rec RawHTMLRecord) error {
+	if rec.Err != nil || len(rec.HtmlBlob) == 0 {
+		return fmt.Errorf("page of %s is not fetched, it is not stored", rec.SymbolName)
 	}
+	_, er := conn.Exec(`INSERT INTO RawHTML (symbolName, html, url, fetched_at, etag, last_modified, content_hash)
+		VALUES (?, ?, ?, ?, ?, ?, ?);`, rec.SymbolName, rec.HtmlBlob, nullable(rec.Url), rec.FetchedAt.Format(time.RFC3339),
+		nullable(rec.ETag), nullable(rec.LastModified), nullable(rec.ContentHash))
+	if er != nil {
+		return fmt.Errorf("cannot insert the page of %s: %w", rec.SymbolName, er)
+	}
+	return nil
+}
+
+func nullable(value string) sql.NullString {
+	return sql.NullString{String: value, Valid: value != ""}
 }
 
 // Only for debug use, Not really useful
@@ -87,21 +148,47 @@ func AddToRawHTML(conn *sql.DB, rec RawHTMLRecord) {
 // }
 
 func AddToFunctionSymbol(conn *sql.DB, declaration function.FunctionDeclarationForInsertion) error {
+	if er := addColumns(conn, "FunctionParameters", usageColumns); er != nil {
+		return er
+	}
+	if er := addColumns(conn, "FunctionSymbols", returnValueColumns); er != nil {
+		return er
+	}
+	if er := addColumns(conn, "FunctionSymbols", renderedDescriptionColumns); er != nil {
+		return er
+	}
+	if er := addColumns(conn, "FunctionParameters", renderedDocumentationColumns); er != nil {
+		return er
+	}
+
 	// Prepare statements within the transaction
-	functionSymbolInsertion, err := conn.Prepare("INSERT OR IGNORE INTO FunctionSymbols (name, arity, return, description, requirements) VALUES (?, ?, ?, ?, ?);")
+	functionSymbolInsertion, err := conn.Prepare(`INSERT OR IGNORE INTO FunctionSymbols (name, arity, return, description, requirements,
+		return_documentation, error_convention, success_value, failure_value, sets_last_error,
+		description_markdown, description_text) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);`)
 	if err != nil {
 		return fmt.Errorf("cannot create functionSymbol insert statement: %w", err)
 	}
 	defer functionSymbolInsertion.Close()
 
-	functionParameter, err := conn.Prepare("INSERT INTO FunctionParameters (function_name, srno, name, datatype, usage, documentation) VALUES (?, ?, ?, ?, ?, ?);")
+	functionParameter, err := conn.Prepare(`INSERT INTO FunctionParameters (function_name, srno, name, datatype, usage, documentation,
+		direction, optional, reserved, size_parameter, size_unit, documentation_markdown, documentation_text)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);`)
 	if err != nil {
 		return fmt.Errorf("cannot create functionParameter insert statement: %w", err)
 	}
 	defer functionParameter.Close()
 
 	// Insert function symbol
-	_, err = functionSymbolInsertion.Exec(declaration.Name, declaration.Arity, declaration.ReturnType, declaration.Description, declaration.Requirements)
+	var (
+		returnValue = declaration.ReturnValue
+		nullable    = func(s string) sql.NullString {
+			return sql.NullString{String: s, Valid: s != ""}
+		}
+	)
+	_, err = functionSymbolInsertion.Exec(declaration.Name, declaration.Arity, declaration.ReturnType, declaration.Description, declaration.Requirements,
+		nullable(returnValue.Documentation), nullable(string(returnValue.Convention)), nullable(returnValue.SuccessValue),
+		nullable(returnValue.FailureValue), returnValue.LastError,
+		nullable(declaration.RenderedDescription.Markdown), nullable(declaration.RenderedDescription.Text))
 	if err != nil {
 		return fmt.Errorf("cannot insert functionSymbol: %w", err)
 	}
@@ -109,15 +196,197 @@ func AddToFunctionSymbol(conn *sql.DB, declaration function.FunctionDeclarationF
 	// Insert parameters
 	for idx, para := range declaration.FunctionDeclaration.Parameters {
 		joined := strings.Join(declaration.ParameterDescription[idx].Value, " ")
-		_, err = functionParameter.Exec(declaration.Name, idx+1, para.Name, para.TypeHint, para.UsageHint, joined)
+		direction, optional, reserved, sizeParameter, sizeUnit := usageValues(para.Usage)
+		markdown, text := renderedValues(declaration.RenderedParameters, idx)
+		_, err = functionParameter.Exec(declaration.Name, idx+1, para.Name, para.TypeHint, para.UsageHint, joined,
+			direction, optional, reserved, sizeParameter, sizeUnit, markdown, text)
 		if err != nil {
 			return fmt.Errorf("cannot insert functionParameter at index %d: %w", idx, err)
 		}
 	}
 
+	if er := addValueConstants(conn, "function", declaration.Name, declaration.ParameterConstants); er != nil {
+		return er
+	}
+	return addRequirements(conn, declaration.Name, declaration.ParsedRequirements)
+}
+
+func addValueConstants(conn *sql.DB, ownerKind, ownerName string, constants utils.AssociativeArray[string, []utils.ValueDefinition]) error {
+	if len(constants) == 0 {
+		return nil
+	}
+	if er := createTables(conn, valueConstantSchema); er != nil {
+		return er
+	}
+
+	constantInsertion, er := conn.Prepare(`INSERT OR REPLACE INTO ValueConstants
+		(owner_kind, owner_name, member_name, srno, name, value, value_text, description) VALUES (?, ?, ?, ?, ?, ?, ?, ?);`)
+	if er != nil {
+		return fmt.Errorf("cannot create valueConstant insert statement: %w", er)
+	}
+	defer constantInsertion.Close()
+
+	for _, member := range constants {
+		for idx, definition := range member.Value {
+			_, er = constantInsertion.Exec(ownerKind, ownerName, member.Key, idx+1,
+				sql.NullString{String: definition.Name, Valid: definition.Name != ""},
+				sql.NullInt64{Int64: definition.Value, Valid: definition.Resolved},
+				sql.NullString{String: definition.ValueText, Valid: definition.ValueText != ""},
+				definition.Description)
+			if er != nil {
+				return fmt.Errorf("cannot insert valueConstant %s of %s: %w", definition.Name, member.Key, er)
+			}
+		}
+	}
 	return nil
 }
 
+func addRequirements(conn *sql.DB, symbolName string, requirements utils.Requirements) error {
+	// Requirements section was not found or could not be parsed, a row of NULLs would say nothing
+	if len(requirements.Raw) == 0 {
+		return nil
+	}
+	if er := createTables(conn, requirementsSchema); er != nil {
+		return er
+	}
+
+	// Items of the previous fill are replaced together, so fewer items do not leave the old ones behind
+	tx, er := conn.Begin()
+	if er != nil {
+		return fmt.Errorf("cannot begin transaction for requirements of %s: %w", symbolName, er)
+	}
+	defer tx.Rollback()
+	if _, er := tx.Exec("DELETE FROM SymbolRequirementItems WHERE symbol_name = ?;", symbolName); er != nil {
+		return fmt.Errorf("cannot remove old requirements of %s: %w", symbolName, er)
+	}
+
+	requirementInsertion, er := tx.Prepare(`INSERT OR REPLACE INTO SymbolRequirements (symbol_name, header, target_platform,
+		min_client, min_client_major, min_client_minor, min_client_build,
+		min_server, min_server_major, min_server_minor, min_server_build) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);`)
+	if er != nil {
+		return fmt.Errorf("cannot create SymbolRequirements insert statement: %w", er)
+	}
+	defer requirementInsertion.Close()
+
+	itemInsertion, er := tx.Prepare("INSERT OR REPLACE INTO SymbolRequirementItems (symbol_name, kind, srno, value) VALUES (?, ?, ?, ?);")
+	if er != nil {
+		return fmt.Errorf("cannot create SymbolRequirementItems insert statement: %w", er)
+	}
+	defer itemInsertion.Close()
+
+	var (
+		nullable = func(s string) sql.NullString {
+			return sql.NullString{String: s, Valid: s != ""}
+		}
+		version = func(v utils.Version) (text sql.NullString, major, minor, build sql.NullInt64) {
+			text = nullable(v.Text)
+			major = sql.NullInt64{Int64: int64(v.Major), Valid: v.Known()}
+			minor = sql.NullInt64{Int64: int64(v.Minor), Valid: v.Known()}
+			build = sql.NullInt64{Int64: int64(v.Build), Valid: v.Known()}
+			return
+		}
+		client, clientMajor, clientMinor, clientBuild = version(requirements.MinimumClient)
+		server, serverMajor, serverMinor, serverBuild = version(requirements.MinimumServer)
+	)
+	_, er = requirementInsertion.Exec(symbolName, nullable(requirements.Header), nullable(requirements.TargetPlatform),
+		client, clientMajor, clientMinor, clientBuild, server, serverMajor, serverMinor, serverBuild)
+	if er != nil {
+		return fmt.Errorf("cannot insert requirements of %s: %w", symbolName, er)
+	}
+
+	items := []struct {
+		kind   string
+		values []string
+	}{
+		{"include", requirements.IncludeChain},
+		{"library", requirements.Libraries},
+		{"dll", requirements.DLLs},
+		{"api_set", requirements.APISets},
+	}
+	for _, item := range items {
+		for i, value := range item.values {
+			if _, er := itemInsertion.Exec(symbolName, item.kind, i+1, value); er != nil {
+				return fmt.Errorf("cannot insert %s requirement of %s: %w", item.kind, symbolName, er)
+			}
+		}
+	}
+	return tx.Commit()
+}
+
+// Values of usageColumns in the same order
+func usageValues(usage function.Usage) (direction sql.NullString, optional, reserved bool, sizeParameter, sizeUnit sql.NullString) {
+	direction = sql.NullString{String: usage.Direction.String(), Valid: usage.Direction != function.DirectionUnknown}
+	optional, reserved = usage.Optional, usage.Reserved
+	sizeParameter = sql.NullString{String: usage.SizeParameter, Valid: usage.SizeParameter != ""}
+	sizeUnit = sql.NullString{String: usage.SizeUnit, Valid: usage.SizeUnit != ""}
+	return
+}
+
+// Parameters which could not be rendered are stored as NULL
+func renderedValues(rendered []utils.Rendered, idx int) (markdown, text sql.NullString) {
+	if idx < len(rendered) {
+		markdown = sql.NullString{String: rendered[idx].Markdown, Valid: true}
+		text = sql.NullString{String: rendered[idx].Text, Valid: true}
+	}
+	return
+}
+
+func AddToCallbackSymbol(conn *sql.DB, declaration callback.CallbackDeclarationForInsertion) error {
+	if er := createTables(conn, callbackSchema); er != nil {
+		return er
+	}
+	if er := addColumns(conn, "CallbackParameters", usageColumns); er != nil {
+		return er
+	}
+	if er := addColumns(conn, "CallbackSymbols", renderedDescriptionColumns); er != nil {
+		return er
+	}
+	if er := addColumns(conn, "CallbackParameters", renderedDocumentationColumns); er != nil {
+		return er
+	}
+
+	callbackSymbolInsertion, err := conn.Prepare(`INSERT OR IGNORE INTO CallbackSymbols
+		(name, function_name, calling_convention, is_pointer, arity, return, description, requirements,
+		description_markdown, description_text) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?);`)
+	if err != nil {
+		return fmt.Errorf("cannot create callbackSymbol insert statement: %w", err)
+	}
+	defer callbackSymbolInsertion.Close()
+
+	callbackParameter, err := conn.Prepare(`INSERT OR IGNORE INTO CallbackParameters (callback_name, srno, name, datatype, usage, documentation,
+		direction, optional, reserved, size_parameter, size_unit, documentation_markdown, documentation_text)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);`)
+	if err != nil {
+		return fmt.Errorf("cannot create callbackParameter insert statement: %w", err)
+	}
+	defer callbackParameter.Close()
+
+	convention := sql.NullString{String: declaration.CallingConvention, Valid: declaration.CallingConvention != ""}
+	_, err = callbackSymbolInsertion.Exec(declaration.TypedefName, declaration.Name, convention, declaration.IsPointer,
+		declaration.Arity, declaration.ReturnType, declaration.Description, declaration.Requirements,
+		sql.NullString{String: declaration.RenderedDescription.Markdown, Valid: declaration.RenderedDescription.Markdown != ""},
+		sql.NullString{String: declaration.RenderedDescription.Text, Valid: declaration.RenderedDescription.Text != ""})
+	if err != nil {
+		return fmt.Errorf("cannot insert callbackSymbol: %w", err)
+	}
+
+	for idx, para := range declaration.Parameters {
+		joined := strings.Join(declaration.ParameterDescription[idx].Value, " ")
+		direction, optional, reserved, sizeParameter, sizeUnit := usageValues(para.Usage)
+		markdown, text := renderedValues(declaration.RenderedParameters, idx)
+		_, err = callbackParameter.Exec(declaration.TypedefName, idx+1, para.Name, para.TypeHint, para.UsageHint, joined,
+			direction, optional, reserved, sizeParameter, sizeUnit, markdown, text)
+		if err != nil {
+			return fmt.Errorf("cannot insert callbackParameter at index %d: %w", idx, err)
+		}
+	}
+
+	if er := addValueConstants(conn, "callback", declaration.TypedefName, declaration.ParameterConstants); er != nil {
+		return er
+	}
+	return addRequirements(conn, declaration.TypedefName, declaration.ParsedRequirements)
+}
+
 func AddToStructSymbol(conn *sql.DB, declarations []structure.StructDeclaration, stdoutbuf *bufio.Writer) error {
 	// These mirrors table schema
 	type (
@@ -130,19 +399,41 @@ func AddToStructSymbol(conn *sql.DB, declarations []structure.StructDeclaration,
 			structure_name string
 			srno           int
 			datatype, name string
+			documentation  utils.Documentation
 		}
 		structurePointer struct {
 			pointer_name, structure_name string
 		}
+		structureField struct {
+			structure_name        string
+			id, srno              int
+			parent_id             sql.NullInt64
+			kind                  string
+			tag, datatype, name   sql.NullString
+			bit_width, dimensions sql.NullString
+		}
 	)
 
-	structureSymbolInsertion, er := conn.Prepare("INSERT INTO StructureSymbols(name, member_count, description, requirement) VALUES (?, ?, ?, ?);")
+	if er := createTables(conn, structureFieldSchema); er != nil {
+		return er
+	}
+
+	if er := addColumns(conn, "StructureSymbols", renderedDescriptionColumns); er != nil {
+		return er
+	}
+	if er := addColumns(conn, "StructureMembers", memberDocumentationColumns); er != nil {
+		return er
+	}
+
+	structureSymbolInsertion, er := conn.Prepare(`INSERT INTO StructureSymbols(name, member_count, description, requirement, description_markdown, description_text)
+		VALUES (?, ?, ?, ?, ?, ?);`)
 	if er != nil {
 		return fmt.Errorf("cannot create StructureSymbol insert statement: %w", er)
 	}
 	defer structureSymbolInsertion.Close()
 
-	structureMemberInsertion, er := conn.Prepare("INSERT INTO StructureMembers(structure_name, srno, datatype, name) VALUES (?, ?, ?, ?);")
+	structureMemberInsertion, er := conn.Prepare(`INSERT INTO StructureMembers(structure_name, srno, datatype, name, documentation, documentation_markdown, documentation_text)
+		VALUES (?, ?, ?, ?, ?, ?, ?);`)
 	if er != nil {
 		return fmt.Errorf("cannot create StructureMembers insert statement: %w", er)
 	}
@@ -154,34 +445,115 @@ func AddToStructSymbol(conn *sql.DB, declarations []structure.StructDeclaration,
 	}
 	defer structurePointerInsertion.Close()
 
+	structureFieldInsertion, er := conn.Prepare(`INSERT OR IGNORE INTO StructureFields
+		(structure_name, id, parent_id, srno, kind, tag, datatype, name, bit_width, dimensions) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?);`)
+	if er != nil {
+		return fmt.Errorf("cannot create StructureFields insert statement: %w", er)
+	}
+	defer structureFieldInsertion.Close()
+
+	nullable := func(s string) sql.NullString {
+		return sql.NullString{String: s, Valid: s != ""}
+	}
+
 	for _, decl := range declarations {
 		pp.Fprintln(stdoutbuf, decl)
+		// First name is the key of every table
+		if len(decl.Names) == 0 {
+			return fmt.Errorf("cannot add %s %s without a name", decl.Kind, decl.StructName)
+		}
 		{
 			value := structureSymbol{
 				name:         decl.Names[0],
 				members:      len(decl.Fields),
-				description:  "",
-				requirements: "",
+				description:  decl.Description.Html,
+				requirements: decl.Requirements,
 			}
-			_, er := structureSymbolInsertion.Exec(value.name, value.members, value.description, value.requirements)
+			_, er := structureSymbolInsertion.Exec(value.name, value.members, value.description, value.requirements,
+				nullable(decl.Description.Markdown), nullable(decl.Description.Text))
 			if er != nil {
 				return fmt.Errorf("Some error in adding structureSymbol: %w", er)
 			}
 		}
 		{
+			documentation := make(map[string]utils.Documentation, len(decl.MemberDocumentation))
+			for _, member := range decl.MemberDocumentation {
+				documentation[member.Key] = member.Value
+			}
 			for i := range decl.Fields {
 				value := structureMembers{
 					structure_name: decl.Names[0],
 					srno:           i + 1,
-					datatype:       decl.Fields[i].Datatype,
+					datatype:       decl.Fields[i].Datatype + decl.Fields[i].DimensionSuffix(),
 					name:           decl.Fields[i].Name,
+					documentation:  documentation[decl.Fields[i].Name],
 				}
-				_, er := structureMemberInsertion.Exec(value.structure_name, value.srno, value.datatype, value.name)
+				_, er := structureMemberInsertion.Exec(value.structure_name, value.srno, value.datatype, value.name, nullable(value.documentation.Html),
+					nullable(value.documentation.Markdown), nullable(value.documentation.Text))
 				if er != nil {
 					return fmt.Errorf("Some error in adding structureMember: %w", er)
 				}
 			}
 		}
+		{
+			// Fields are numbered in preorder so that parent is always inserted before its children
+			var (
+				id   int
+				walk func(aggregate *structure.Aggregate, parent int) error
+			)
+			walk = func(aggregate *structure.Aggregate, parent int) error {
+				for i, field := range aggregate.Fields {
+					id += 1
+					value := structureField{
+						structure_name: decl.Names[0],
+						id:             id,
+						parent_id:      sql.NullInt64{Int64: int64(parent), Valid: true},
+						srno:           i + 1,
+						kind:           "field",
+						datatype:       nullable(field.Datatype),
+						name:           nullable(field.Name),
+						bit_width:      nullable(field.BitWidth),
+						dimensions:     nullable(field.DimensionSuffix()),
+					}
+					if field.Inner != nil {
+						value.kind = field.Inner.Kind
+						value.tag = nullable(field.Inner.StructName)
+					}
+					_, er := structureFieldInsertion.Exec(value.structure_name, value.id, value.parent_id, value.srno, value.kind,
+						value.tag, value.datatype, value.name, value.bit_width, value.dimensions)
+					if er != nil {
+						return fmt.Errorf("Some error in adding structureField: %w", er)
+					}
+					if field.Inner != nil {
+						if er := walk(field.Inner, value.id); er != nil {
+							return er
+						}
+					}
+				}
+				return nil
+			}
+
+			root := structureField{
+				structure_name: decl.Names[0],
+				kind:           decl.Kind,
+				tag:            nullable(decl.StructName),
+				name:           nullable(decl.Names[0]),
+			}
+			_, er := structureFieldInsertion.Exec(root.structure_name, root.id, root.parent_id, root.srno, root.kind,
+				root.tag, root.datatype, root.name, root.bit_width, root.dimensions)
+			if er != nil {
+				return fmt.Errorf("Some error in adding structureField: %w", er)
+			}
+			if er := walk(&decl.Aggregate, 0); er != nil {
+				return er
+			}
+		}
+		if er := addValueConstants(conn, "structure", decl.Names[0], decl.MemberConstants); er != nil {
+			return er
+		}
+		if er := addRequirements(conn, decl.Names[0], decl.ParsedRequirements); er != nil {
+			return er
+		}
 		if len(decl.Names) > 1 {
 			for _, n := range decl.Names[1:] {
 				value := structurePointer{
@@ -197,3 +569,911 @@ func AddToStructSymbol(conn *sql.DB, declarations []structure.StructDeclaration,
 	}
 	return nil
 }
+
+func AddToEnumSymbol(conn *sql.DB, declarations []enumeration.EnumDeclaration) error {
+	if er := createTables(conn, enumerationSchema); er != nil {
+		return er
+	}
+
+	enumSymbolInsertion, er := conn.Prepare("INSERT OR IGNORE INTO EnumSymbols(name, tag, constant_count, description) VALUES (?, ?, ?, ?);")
+	if er != nil {
+		return fmt.Errorf("cannot create EnumSymbols insert statement: %w", er)
+	}
+	defer enumSymbolInsertion.Close()
+
+	enumConstantInsertion, er := conn.Prepare("INSERT OR IGNORE INTO EnumConstants(enum_name, srno, name, value, expression, documentation) VALUES (?, ?, ?, ?, ?, ?);")
+	if er != nil {
+		return fmt.Errorf("cannot create EnumConstants insert statement: %w", er)
+	}
+	defer enumConstantInsertion.Close()
+
+	for _, decl := range declarations {
+		tag := sql.NullString{String: decl.EnumName, Valid: decl.EnumName != ""}
+		if _, er := enumSymbolInsertion.Exec(decl.Names[0], tag, len(decl.Constants), decl.Description); er != nil {
+			return fmt.Errorf("Some error in adding enumSymbol: %w", er)
+		}
+		for i, constant := range decl.Constants {
+			var (
+				value      = sql.NullInt64{Int64: constant.Value, Valid: constant.Resolved}
+				expression = sql.NullString{String: constant.Expression, Valid: constant.Expression != ""}
+			)
+			_, er := enumConstantInsertion.Exec(decl.Names[0], i+1, constant.Name, value, expression, constant.Documentation)
+			if er != nil {
+				return fmt.Errorf("Some error in adding enumConstant: %w", er)
+			}
+		}
+	}
+	return nil
+}
+
+// Sections which are stored as they are, rest of the sections are stored in the tables of their symbol type
+var StoredSections = []string{"remarks", "see-also"}
+
+// Sections whose links are added to SymbolReferences
+var ReferenceSections = []string{"basic-description", "parameters", "members", "constants", "return-value", "remarks", "see-also"}
+
+// Path of every page in Symbol table mapped to name of the symbol, used to resolve the links
+func SymbolPaths(conn *sql.DB) (map[string]string, error) {
+	rows, er := conn.Query("SELECT name, url FROM Symbol;")
+	if er != nil {
+		return nil, fmt.Errorf("cannot query Symbol table: %w", er)
+	}
+	defer rows.Close()
+
+	paths := make(map[string]string)
+	for rows.Next() {
+		var name, url string
+		if er := rows.Scan(&name, &url); er != nil {
+			return nil, fmt.Errorf("cannot scan Symbol table: %w", er)
+		}
+		if path, internal := utils.ResolveLink("/", url); internal {
+			paths[path] = name
+		}
+	}
+	return paths, rows.Err()
+}
+
+// Stores remarks and see-also sections of the page at path, and all the links found in its documentation
+func AddToReferences(conn *sql.DB, symbolName, path string, sections map[string][]*goquery.Selection, paths map[string]string) error {
+	if er := createTables(conn, referenceSchema); er != nil {
+		return er
+	}
+
+	// Rows of the previous fill are replaced together, so fewer sections or links do not leave the old ones behind
+	tx, er := conn.Begin()
+	if er != nil {
+		return fmt.Errorf("cannot begin transaction for references of %s: %w", symbolName, er)
+	}
+	defer tx.Rollback()
+	if _, er := tx.Exec("DELETE FROM SymbolSections WHERE symbol_name = ?;", symbolName); er != nil {
+		return fmt.Errorf("cannot remove old sections of %s: %w", symbolName, er)
+	}
+	if _, er := tx.Exec("DELETE FROM SymbolReferences WHERE source = ?;", symbolName); er != nil {
+		return fmt.Errorf("cannot remove old references of %s: %w", symbolName, er)
+	}
+
+	sectionInsertion, er := tx.Prepare("INSERT OR REPLACE INTO SymbolSections(symbol_name, section, content) VALUES (?, ?, ?);")
+	if er != nil {
+		return fmt.Errorf("cannot create SymbolSections insert statement: %w", er)
+	}
+	defer sectionInsertion.Close()
+
+	referenceInsertion, er := tx.Prepare(`INSERT OR REPLACE INTO SymbolReferences
+		(source, section, srno, text, href, target_path, target) VALUES (?, ?, ?, ?, ?, ?, ?);`)
+	if er != nil {
+		return fmt.Errorf("cannot create SymbolReferences insert statement: %w", er)
+	}
+	defer referenceInsertion.Close()
+
+	for _, section := range StoredSections {
+		if blocks, found := sections[section]; found {
+			if _, er := sectionInsertion.Exec(symbolName, section, utils.JoinBlocks(blocks)); er != nil {
+				return fmt.Errorf("cannot insert %s section of %s: %w", section, symbolName, er)
+			}
+		}
+	}
+
+	for _, section := range ReferenceSections {
+		for idx, link := range utils.ExtractLinks(sections[section]) {
+			var targetPath, target sql.NullString
+			if resolved, internal := utils.ResolveLink(path, link.Href); internal {
+				targetPath = sql.NullString{String: resolved, Valid: true}
+				target.String, target.Valid = paths[resolved]
+			}
+			_, er := referenceInsertion.Exec(symbolName, section, idx+1, link.Text, link.Href, targetPath, target)
+			if er != nil {
+				return fmt.Errorf("cannot insert reference %s of %s: %w", link.Href, symbolName, er)
+			}
+		}
+	}
+	return tx.Commit()
+}
+
+// Also removes the macro from FunctionSymbols, when it was filled as function before macros were recognized
+func AddToMacroSymbol(conn *sql.DB, declaration macro.MacroDeclarationForInsertion) error {
+	if er := createTables(conn, macroSchema); er != nil {
+		return er
+	}
+
+	macroSymbolInsertion, er := conn.Prepare(`INSERT OR REPLACE INTO MacroSymbols
+		(name, parameters, body, return, description, description_markdown, description_text, requirements) VALUES (?, ?, ?, ?, ?, ?, ?, ?);`)
+	if er != nil {
+		return fmt.Errorf("cannot create macroSymbol insert statement: %w", er)
+	}
+	defer macroSymbolInsertion.Close()
+
+	macroParameter, er := conn.Prepare(`INSERT OR REPLACE INTO MacroParameters
+		(macro_name, srno, name, datatype, usage, documentation, documentation_markdown, documentation_text) VALUES (?, ?, ?, ?, ?, ?, ?, ?);`)
+	if er != nil {
+		return fmt.Errorf("cannot create macroParameter insert statement: %w", er)
+	}
+	defer macroParameter.Close()
+
+	var (
+		nullable = func(s string) sql.NullString {
+			return sql.NullString{String: s, Valid: s != ""}
+		}
+		parameters sql.NullString
+		returnType sql.NullString
+	)
+	if declaration.Parameters != nil {
+		parameters = sql.NullString{String: strings.Join(declaration.Parameters, ", "), Valid: true}
+	}
+	if declaration.Signature != nil {
+		returnType = nullable(declaration.Signature.ReturnType)
+	}
+	_, er = macroSymbolInsertion.Exec(declaration.Name, parameters, nullable(declaration.Body), returnType, declaration.Description,
+		nullable(declaration.RenderedDescription.Markdown), nullable(declaration.RenderedDescription.Text), declaration.Requirements)
+	if er != nil {
+		return fmt.Errorf("cannot insert macroSymbol: %w", er)
+	}
+
+	if declaration.Signature != nil {
+		for idx, para := range declaration.Signature.Parameters {
+			var joined string
+			if idx < len(declaration.ParameterDescription) {
+				joined = strings.Join(declaration.ParameterDescription[idx].Value, " ")
+			}
+			markdown, text := renderedValues(declaration.RenderedParameters, idx)
+			_, er = macroParameter.Exec(declaration.Name, idx+1, para.Name, nullable(para.TypeHint), para.UsageHint, joined, markdown, text)
+			if er != nil {
+				return fmt.Errorf("cannot insert macroParameter at index %d: %w", idx, er)
+			}
+		}
+	}
+
+	if _, er := conn.Exec("DELETE FROM FunctionParameters WHERE function_name = ?;", declaration.Name); er != nil {
+		return fmt.Errorf("cannot remove macro %s from FunctionParameters: %w", declaration.Name, er)
+	}
+	if _, er := conn.Exec("DELETE FROM FunctionSymbols WHERE name = ?;", declaration.Name); er != nil {
+		return fmt.Errorf("cannot remove macro %s from FunctionSymbols: %w", declaration.Name, er)
+	}
+	return addRequirements(conn, declaration.Name, declaration.ParsedRequirements)
+}
+
+func AddToInterfaceSymbol(conn *sql.DB, declaration cominterface.InterfaceDeclarationForInsertion) error {
+	if er := createTables(conn, interfaceSchema); er != nil {
+		return er
+	}
+
+	interfaceSymbolInsertion, er := conn.Prepare(`INSERT OR REPLACE INTO InterfaceSymbols
+		(name, iid, base_interface, method_count, description, description_markdown, description_text, requirements) VALUES (?, ?, ?, ?, ?, ?, ?, ?);`)
+	if er != nil {
+		return fmt.Errorf("cannot create interfaceSymbol insert statement: %w", er)
+	}
+	defer interfaceSymbolInsertion.Close()
+
+	// Signature of the methods which are already filled is kept
+	interfaceMethodInsertion, er := conn.Prepare(`INSERT INTO InterfaceMethods (interface_name, srno, name, href, target_path, description)
+		VALUES (?, ?, ?, ?, ?, ?) ON CONFLICT (interface_name, srno) DO UPDATE SET
+		name = excluded.name, href = excluded.href, target_path = excluded.target_path, description = excluded.description;`)
+	if er != nil {
+		return fmt.Errorf("cannot create interfaceMethod insert statement: %w", er)
+	}
+	defer interfaceMethodInsertion.Close()
+
+	nullable := func(s string) sql.NullString {
+		return sql.NullString{String: s, Valid: s != ""}
+	}
+	_, er = interfaceSymbolInsertion.Exec(declaration.Name, nullable(declaration.IID), nullable(declaration.BaseInterface), len(declaration.Methods),
+		declaration.Description, nullable(declaration.RenderedDescription.Markdown), nullable(declaration.RenderedDescription.Text), declaration.Requirements)
+	if er != nil {
+		return fmt.Errorf("cannot insert interfaceSymbol: %w", er)
+	}
+
+	for idx, method := range declaration.Methods {
+		_, er := interfaceMethodInsertion.Exec(declaration.Name, idx+1, method.Name, nullable(method.Href), nullable(method.Path), method.Description)
+		if er != nil {
+			return fmt.Errorf("cannot insert interfaceMethod %s: %w", method.Name, er)
+		}
+	}
+	if _, er := conn.Exec("DELETE FROM InterfaceMethods WHERE interface_name = ? AND srno > ?;", declaration.Name, len(declaration.Methods)); er != nil {
+		return fmt.Errorf("cannot remove old methods of %s: %w", declaration.Name, er)
+	}
+	return addRequirements(conn, declaration.Name, declaration.ParsedRequirements)
+}
+
+// Fills the signature of a method already added by AddToInterfaceSymbol
+func AddToInterfaceMethod(conn *sql.DB, declaration cominterface.MethodDeclarationForInsertion) error {
+	if er := createTables(conn, interfaceSchema); er != nil {
+		return er
+	}
+
+	methodParameter, er := conn.Prepare(`INSERT OR REPLACE INTO InterfaceMethodParameters (interface_name, method_name, srno, name, datatype, usage, documentation,
+		direction, optional, reserved, size_parameter, size_unit, documentation_markdown, documentation_text)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);`)
+	if er != nil {
+		return fmt.Errorf("cannot create interfaceMethodParameter insert statement: %w", er)
+	}
+	defer methodParameter.Close()
+
+	convention := sql.NullString{String: declaration.CallingConvention, Valid: declaration.CallingConvention != ""}
+	result, er := conn.Exec(`UPDATE InterfaceMethods SET return = ?, arity = ?, calling_convention = ?, return_documentation = ?
+		WHERE interface_name = ? AND name = ?;`, declaration.ReturnType, declaration.Arity, convention, declaration.ReturnValue.Documentation,
+		declaration.Interface, declaration.Name)
+	if er != nil {
+		return fmt.Errorf("cannot update interfaceMethod: %w", er)
+	}
+	if updated, _ := result.RowsAffected(); updated == 0 {
+		return fmt.Errorf("method %s::%s is not present in InterfaceMethods", declaration.Interface, declaration.Name)
+	}
+
+	for idx, para := range declaration.Parameters {
+		var joined string
+		if idx < len(declaration.ParameterDescription) {
+			joined = strings.Join(declaration.ParameterDescription[idx].Value, " ")
+		}
+		direction, optional, reserved, sizeParameter, sizeUnit := usageValues(para.Usage)
+		markdown, text := renderedValues(declaration.RenderedParameters, idx)
+		_, er = methodParameter.Exec(declaration.Interface, declaration.Name, idx+1, para.Name, para.TypeHint, para.UsageHint, joined,
+			direction, optional, reserved, sizeParameter, sizeUnit, markdown, text)
+		if er != nil {
+			return fmt.Errorf("cannot insert interfaceMethodParameter at index %d: %w", idx, er)
+		}
+	}
+	return nil
+}
+
+// Pages of the interface methods which are not yet scraped, named as `IClassFactory::CreateInstance`
+func InterfaceMethodRecords(conn *sql.DB) ([]SymbolRecord, error) {
+	if er := createTables(conn, interfaceSchema); er != nil {
+		return nil, er
+	}
+	return RunQuery(conn, `SELECT '', InterfaceMethods.interface_name || '::' || InterfaceMethods.name, 'method', InterfaceMethods.target_path
+		FROM InterfaceMethods WHERE InterfaceMethods.target_path IS NOT NULL AND
+		InterfaceMethods.interface_name || '::' || InterfaceMethods.name NOT IN (SELECT symbolName FROM RawHTML);`), nil
+}
+
+// Members and methods of the class are replaced on every call
+func AddToClassSymbol(conn *sql.DB, declaration class.ClassDeclarationForInsertion) error {
+	if er := createTables(conn, classSchema); er != nil {
+		return er
+	}
+
+	classSymbolInsertion, er := conn.Prepare(`INSERT OR REPLACE INTO ClassSymbols
+		(name, base_classes, member_count, method_count, description, description_markdown, description_text, requirements)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?);`)
+	if er != nil {
+		return fmt.Errorf("cannot create classSymbol insert statement: %w", er)
+	}
+	defer classSymbolInsertion.Close()
+
+	classMemberInsertion, er := conn.Prepare(`INSERT INTO ClassMembers (class_name, srno, name, datatype, bit_width, dimensions)
+		VALUES (?, ?, ?, ?, ?, ?);`)
+	if er != nil {
+		return fmt.Errorf("cannot create classMember insert statement: %w", er)
+	}
+	defer classMemberInsertion.Close()
+
+	classMethodInsertion, er := conn.Prepare(`INSERT INTO ClassMethods (class_name, srno, name, overload, kind, href, target_path, description)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?);`)
+	if er != nil {
+		return fmt.Errorf("cannot create classMethod insert statement: %w", er)
+	}
+	defer classMethodInsertion.Close()
+
+	nullable := func(s string) sql.NullString {
+		return sql.NullString{String: s, Valid: s != ""}
+	}
+	_, er = classSymbolInsertion.Exec(declaration.Name, nullable(strings.Join(declaration.BaseClasses, ", ")), len(declaration.Members),
+		len(declaration.Methods), declaration.Description, nullable(declaration.RenderedDescription.Markdown),
+		nullable(declaration.RenderedDescription.Text), declaration.Requirements)
+	if er != nil {
+		return fmt.Errorf("cannot insert classSymbol: %w", er)
+	}
+
+	for _, table := range []string{"ClassMembers", "ClassMethods"} {
+		if _, er := conn.Exec(fmt.Sprintf("DELETE FROM %s WHERE class_name = ?;", table), declaration.Name); er != nil {
+			return fmt.Errorf("cannot remove old rows of %s from %s: %w", declaration.Name, table, er)
+		}
+	}
+	for idx, member := range declaration.Members {
+		_, er := classMemberInsertion.Exec(declaration.Name, idx+1, nullable(member.Name), member.Datatype,
+			nullable(member.BitWidth), nullable(member.DimensionSuffix()))
+		if er != nil {
+			return fmt.Errorf("cannot insert classMember at index %d: %w", idx, er)
+		}
+	}
+	for idx, method := range declaration.Methods {
+		_, er := classMethodInsertion.Exec(declaration.Name, idx+1, method.Name, nullable(method.Overload), string(method.Kind),
+			nullable(method.Href), nullable(method.Path), method.Description)
+		if er != nil {
+			return fmt.Errorf("cannot insert classMethod %s: %w", method.Name, er)
+		}
+	}
+	return addRequirements(conn, declaration.Name, declaration.ParsedRequirements)
+}
+
+// Type expressions as written in the filled parameters, return types and members, like `LPCWSTR` or `struct _FOO *`
+func ReferencedTypes(conn *sql.DB) []string {
+	sources := []struct{ table, query string }{
+		{"FunctionParameters", "SELECT datatype FROM FunctionParameters"},
+		{"FunctionSymbols", "SELECT return FROM FunctionSymbols"},
+		{"CallbackParameters", "SELECT datatype FROM CallbackParameters"},
+		{"CallbackSymbols", "SELECT return FROM CallbackSymbols"},
+		{"StructureFields", "SELECT datatype FROM StructureFields WHERE kind = 'field'"},
+		{"InterfaceMethodParameters", "SELECT datatype FROM InterfaceMethodParameters"},
+		{"InterfaceMethods", "SELECT return FROM InterfaceMethods"},
+		{"ClassMembers", "SELECT datatype FROM ClassMembers"},
+	}
+	var queries []string
+	for _, source := range sources {
+		if tableExists(conn, source.table) {
+			queries = append(queries, source.query)
+		}
+	}
+	if len(queries) == 0 {
+		return nil
+	}
+
+	rows, er := conn.Query(fmt.Sprintf("SELECT DISTINCT datatype FROM (%s) WHERE datatype IS NOT NULL;", strings.Join(queries, " UNION ")))
+	if er != nil {
+		log.Panicf("Cannot query the referenced types: %s\n", er)
+	}
+	defer rows.Close()
+	var expressions []string
+	for rows.Next() {
+		var expression string
+		if er := rows.Scan(&expression); er != nil {
+			log.Panicf("Cannot scan the referenced types: %s\n", er)
+		}
+		expressions = append(expressions, expression)
+	}
+	return expressions
+}
+
+// Records of Symbol table with the given names, which are not present in RawHTML
+func UnscrapedSymbols(conn *sql.DB, names []string) []SymbolRecord {
+	encoded, er := json.Marshal(names)
+	if er != nil {
+		log.Panicln("Cannot encode the names: ", er)
+	}
+	rows, er := conn.Query(`SELECT Symbol.header, Symbol.name, Symbol.type, Symbol.url FROM Symbol
+		WHERE Symbol.name IN (SELECT value FROM json_each(?)) AND Symbol.name NOT IN (SELECT symbolName FROM RawHTML)
+		GROUP BY Symbol.name;`, string(encoded))
+	if er != nil {
+		log.Panicf("Cannot query Symbol table: %s\n", er)
+	}
+	defer rows.Close()
+
+	var records []SymbolRecord
+	for rows.Next() {
+		var record SymbolRecord
+		if er := rows.Scan(&record.Header, &record.Name, &record.Ttype, &record.Url); er != nil {
+			log.Panicf("Cannot scan Symbol table: %s\n", er)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+// Values of the column, it is empty when the table is not created yet
+func FilledNames(conn *sql.DB, table, column string) map[string]bool {
+	names := make(map[string]bool)
+	if !tableExists(conn, table) {
+		return names
+	}
+	rows, er := conn.Query(fmt.Sprintf("SELECT %s FROM %s;", column, table))
+	if er != nil {
+		log.Panicf("Cannot query %s table: %s\n", table, er)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		if er := rows.Scan(&name); er != nil {
+			log.Panicf("Cannot scan %s table: %s\n", table, er)
+		}
+		names[name] = true
+	}
+	return names
+}
+
+func tableExists(conn *sql.DB, table string) bool {
+	var count int
+	if er := conn.QueryRow(`SELECT count(*) FROM sqlite_master WHERE type = 'table' AND name = ?;`, table).Scan(&count); er != nil {
+		log.Panicf("Query of sqlite_master failed due to: %v", er)
+	}
+	return count > 0
+}
+
+// Selection of the Symbol table, a symbol is selected when it matches any of them
+type Seeds struct {
+	// As in Headers table, compared without case
+	Headers []string
+	// DLLs from SymbolRequirementItems, so only the pages filled before can be selected by them
+	DLLs  []string
+	Names []string
+}
+
+// Records of Symbol table selected by the seeds, which are not present in RawHTML
+func SeedSymbols(conn *sql.DB, seeds Seeds) []SymbolRecord {
+	encode := func(values []string) string {
+		lowered := make([]string, 0, len(values))
+		for _, value := range values {
+			lowered = append(lowered, strings.ToLower(value))
+		}
+		encoded, er := json.Marshal(lowered)
+		if er != nil {
+			log.Panicln("Cannot encode the seeds: ", er)
+		}
+		return string(encoded)
+	}
+
+	conditions := []string{
+		"lower(Symbol.header) IN (SELECT value FROM json_each(?1))",
+		"lower(Symbol.name) IN (SELECT value FROM json_each(?3))",
+	}
+	if len(seeds.DLLs) > 0 {
+		if tableExists(conn, "SymbolRequirementItems") {
+			conditions = append(conditions, `Symbol.name IN (SELECT symbol_name FROM SymbolRequirementItems
+				WHERE kind = 'dll' AND lower(value) IN (SELECT value FROM json_each(?2)))`)
+		} else {
+			log.Println("DLLs are only known for the filled pages, fill them before selecting by DLL")
+		}
+	}
+	rows, er := conn.Query(fmt.Sprintf(`SELECT Symbol.header, Symbol.name, Symbol.type, Symbol.url FROM Symbol
+		WHERE (%s) AND Symbol.name NOT IN (SELECT symbolName FROM RawHTML) GROUP BY Symbol.name;`, strings.Join(conditions, " OR ")),
+		encode(seeds.Headers), encode(seeds.DLLs), encode(seeds.Names))
+	if er != nil {
+		log.Panicf("Cannot query Symbol table: %s\n", er)
+	}
+	defer rows.Close()
+
+	var records []SymbolRecord
+	for rows.Next() {
+		var record SymbolRecord
+		if er := rows.Scan(&record.Header, &record.Name, &record.Ttype, &record.Url); er != nil {
+			log.Panicf("Cannot scan Symbol table: %s\n", er)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+// Selection of the Symbol table by type and an SQL condition, both have to match when given
+type SymbolFilter struct {
+	// Compared without case, `callback` also matches the types like `callback function`
+	Types []string
+	// Used as it is in the WHERE clause of the query on Symbol table
+	Where string
+}
+
+// Records of Symbol table selected by the filter, which are not present in RawHTML. Error is returned for a
+// wrong condition instead of panicking, as it is given by the user.
+func FilteredSymbols(conn *sql.DB, filter SymbolFilter) ([]SymbolRecord, error) {
+	var (
+		conditions = []string{"Symbol.name NOT IN (SELECT symbolName FROM RawHTML)"}
+		args       []any
+	)
+	if len(filter.Types) > 0 {
+		lowered := make([]string, 0, len(filter.Types))
+		for _, t := range filter.Types {
+			lowered = append(lowered, strings.ToLower(t))
+		}
+		encoded, er := json.Marshal(lowered)
+		if er != nil {
+			return nil, fmt.Errorf("cannot encode the types: %w", er)
+		}
+		conditions = append(conditions, `EXISTS (SELECT 1 FROM json_each(?) AS t
+			WHERE lower(Symbol.type) = t.value OR lower(Symbol.type) LIKE t.value || ' %')`)
+		args = append(args, string(encoded))
+	}
+	if filter.Where != "" {
+		conditions = append(conditions, "("+filter.Where+")")
+	}
+
+	rows, er := conn.Query(fmt.Sprintf(`SELECT Symbol.header, Symbol.name, Symbol.type, Symbol.url FROM Symbol
+		WHERE %s GROUP BY Symbol.name;`, strings.Join(conditions, " AND ")), args...)
+	if er != nil {
+		return nil, fmt.Errorf("cannot query Symbol table: %w", er)
+	}
+	defer rows.Close()
+
+	var records []SymbolRecord
+	for rows.Next() {
+		var record SymbolRecord
+		if er := rows.Scan(&record.Header, &record.Name, &record.Ttype, &record.Url); er != nil {
+			return nil, fmt.Errorf("cannot scan Symbol table: %w", er)
+		}
+		records = append(records, record)
+	}
+	return records, rows.Err()
+}
+
+// Stores the toc.json of the header, replacing the one fetched before
+func AddToHeaders(ctx context.Context, conn *sql.DB, name, url string, json_blob []byte) error {
+	if er := createTables(conn, headerSchema); er != nil {
+		return er
+	}
+	if er := addColumns(conn, "Headers", headerColumns); er != nil {
+		return er
+	}
+	tx, er := conn.BeginTx(ctx, nil)
+	if er != nil {
+		return fmt.Errorf("cannot begin transaction for %s: %w", name, er)
+	}
+	defer tx.Rollback()
+
+	if _, er := tx.ExecContext(ctx, `DELETE FROM Headers WHERE name = ?;`, name); er != nil {
+		return fmt.Errorf("cannot delete old toc of %s: %w", name, er)
+	}
+	if _, er := tx.ExecContext(ctx, `INSERT INTO Headers (name, json_blob, url) VALUES (?, ?, ?);`, name, json_blob, url); er != nil {
+		return fmt.Errorf("cannot insert toc of %s: %w", name, er)
+	}
+	return tx.Commit()
+}
+
+// Replaces the symbols of the header, so filling from the same toc.json again gives the same rows
+func ReplaceHeaderSymbols(ctx context.Context, conn *sql.DB, header string, records []SymbolRecord) error {
+	if er := createTables(conn, headerSchema); er != nil {
+		return er
+	}
+	tx, er := conn.BeginTx(ctx, nil)
+	if er != nil {
+		return fmt.Errorf("cannot begin transaction for %s: %w", header, er)
+	}
+	defer tx.Rollback()
+
+	if _, er := tx.ExecContext(ctx, `DELETE FROM Symbol WHERE header = ?;`, header); er != nil {
+		return fmt.Errorf("cannot delete old symbols of %s: %w", header, er)
+	}
+	stmt, er := tx.PrepareContext(ctx, `INSERT INTO Symbol (header, name, type, url) VALUES (?, ?, ?, ?);`)
+	if er != nil {
+		return fmt.Errorf("cannot prepare insert into Symbol: %w", er)
+	}
+	defer stmt.Close()
+	for _, record := range records {
+		if _, er := stmt.ExecContext(ctx, record.Header, record.Name, record.Ttype, record.Url); er != nil {
+			return fmt.Errorf("cannot insert %s of %s: %w", record.Name, header, er)
+		}
+	}
+	return tx.Commit()
+}
+
+// toc.json of a header as stored in Headers table, Url is empty for the ones stored before discovery
+type HeaderTOC struct {
+	Name, Url string
+	JsonBlob  []byte
+}
+
+func StoredHeaders(conn *sql.DB) []HeaderTOC {
+	if er := createTables(conn, headerSchema); er != nil {
+		log.Panicln(er)
+	}
+	if er := addColumns(conn, "Headers", headerColumns); er != nil {
+		log.Panicln(er)
+	}
+	rows, er := conn.Query(`SELECT name, json_blob, ifnull(url, '') FROM Headers ORDER BY name;`)
+	if er != nil {
+		log.Panicf("Cannot query Headers table: %s\n", er)
+	}
+	defer rows.Close()
+
+	var headers []HeaderTOC
+	for rows.Next() {
+		var header HeaderTOC
+		if er := rows.Scan(&header.Name, &header.JsonBlob, &header.Url); er != nil {
+			log.Panicf("Cannot scan Headers table: %s\n", er)
+		}
+		headers = append(headers, header)
+	}
+	return headers
+}
+
+// Pages in RawHTML with the url to fetch them from, which is of Symbol or InterfaceMethods and the url they were
+// fetched from for the rest. Only the pages fetched before the given time are returned, zero time returns all.
+func StoredPages(conn *sql.DB, fetchedBefore time.Time) ([]PageRequest, error) {
+	if er := addColumns(conn, "RawHTML", rawHTMLColumns); er != nil {
+		return nil, er
+	}
+	methodPath := "NULL"
+	if tableExists(conn, "InterfaceMethods") {
+		methodPath = `(SELECT target_path FROM InterfaceMethods WHERE interface_name || '::' || name = RawHTML.symbolName)`
+	}
+	before := ""
+	if !fetchedBefore.IsZero() {
+		before = fetchedBefore.UTC().Format(time.RFC3339)
+	}
+	rows, er := conn.Query(fmt.Sprintf(`SELECT RawHTML.symbolName, ifnull(RawHTML.url, ''),
+		ifnull((SELECT url FROM Symbol WHERE Symbol.name = RawHTML.symbolName), ifnull(%s, '')),
+		ifnull(RawHTML.etag, ''), ifnull(RawHTML.last_modified, '')
+		FROM RawHTML WHERE ?1 = '' OR RawHTML.fetched_at IS NULL OR RawHTML.fetched_at < ?1
+		GROUP BY RawHTML.symbolName;`, methodPath), before)
+	if er != nil {
+		return nil, fmt.Errorf("cannot query RawHTML table: %w", er)
+	}
+	defer rows.Close()
+
+	var pages []PageRequest
+	for rows.Next() {
+		var (
+			page PageRequest
+			path string
+		)
+		if er := rows.Scan(&page.Name, &page.Url, &path, &page.ETag, &page.LastModified); er != nil {
+			return nil, fmt.Errorf("cannot scan RawHTML table: %w", er)
+		}
+		// Pages are fetched again from the current source, the stored url is used only when path is unknown
+		if path != "" {
+			page.Url = (&SymbolRecord{Url: path}).ScrapableUrl()
+		}
+		if page.Url != "" {
+			pages = append(pages, page)
+		}
+	}
+	return pages, rows.Err()
+}
+
+// Main content of the page at path (in the form of Symbol table) as stored in RawHTML and the hash of it, found is
+// false when the page is not scraped
+func StoredPage(conn *sql.DB, path string) (html []byte, contentHash string, found bool, er error) {
+	if er := addColumns(conn, "RawHTML", rawHTMLColumns); er != nil {
+		return nil, "", false, er
+	}
+	methodName := "NULL"
+	if tableExists(conn, "InterfaceMethods") {
+		methodName = `(SELECT interface_name || '::' || name FROM InterfaceMethods WHERE lower(target_path) = ?1)`
+	}
+	var data string
+	er = conn.QueryRow(fmt.Sprintf(`SELECT html, ifnull(content_hash, '') FROM RawHTML
+		WHERE symbolName IN (SELECT name FROM Symbol WHERE lower(url) = ?1) OR symbolName = %s LIMIT 1;`, methodName),
+		path).Scan(&data, &contentHash)
+	if errors.Is(er, sql.ErrNoRows) {
+		return nil, "", false, nil
+	} else if er != nil {
+		return nil, "", false, fmt.Errorf("cannot query RawHTML table: %w", er)
+	}
+	html, er = GetDecompressed(data)
+	if er != nil {
+		return nil, "", false, fmt.Errorf("cannot decompress page of %s: %w", path, er)
+	}
+	return html, contentHash, true, nil
+}
+
+// Updates the stored page with the refreshed one. Page is replaced only when its main content changed, then the
+// records parsed from it are marked in StaleSymbols. changed tells if it was replaced.
+func RefreshRawHTML(ctx context.Context, conn *sql.DB, rec RawHTMLRecord) (changed bool, er error) {
+	if er := createTables(conn, staleSchema); er != nil {
+		return false, er
+	}
+	tx, er := conn.BeginTx(ctx, nil)
+	if er != nil {
+		return false, fmt.Errorf("cannot begin transaction for %s: %w", rec.SymbolName, er)
+	}
+	defer tx.Rollback()
+
+	var storedHash string
+	er = tx.QueryRowContext(ctx, `SELECT ifnull(content_hash, '') FROM RawHTML WHERE symbolName = ? LIMIT 1;`, rec.SymbolName).Scan(&storedHash)
+	if er != nil {
+		return false, fmt.Errorf("cannot read the stored page of %s: %w", rec.SymbolName, er)
+	}
+	fetchedAt := rec.FetchedAt.Format(time.RFC3339)
+	changed = !rec.NotModified && rec.ContentHash != storedHash
+
+	if changed {
+		_, er = tx.ExecContext(ctx, `UPDATE RawHTML SET html = ?, url = ?, fetched_at = ?, etag = ?, last_modified = ?, content_hash = ?
+			WHERE symbolName = ?;`, rec.HtmlBlob, nullable(rec.Url), fetchedAt, nullable(rec.ETag), nullable(rec.LastModified),
+			rec.ContentHash, rec.SymbolName)
+		if er == nil {
+			_, er = tx.ExecContext(ctx, `INSERT OR REPLACE INTO StaleSymbols (symbol_name, changed_at) VALUES (?, ?);`, rec.SymbolName, fetchedAt)
+		}
+	} else {
+		// Hash of the pages stored before the metadata is filled when they are found unchanged
+		_, er = tx.ExecContext(ctx, `UPDATE RawHTML SET url = ?, fetched_at = ?, etag = ?, last_modified = ?
+			WHERE symbolName = ?;`, nullable(rec.Url), fetchedAt, nullable(rec.ETag), nullable(rec.LastModified), rec.SymbolName)
+	}
+	if er != nil {
+		return false, fmt.Errorf("cannot update the page of %s: %w", rec.SymbolName, er)
+	}
+	return changed, tx.Commit()
+}
+
+// Tables filled from the pages with the column naming the page they are filled from
+var parsedTables = []struct{ table, page string }{
+	{"FunctionSymbols", "name"},
+	{"FunctionParameters", "function_name"},
+	{"CallbackSymbols", "name"},
+	{"CallbackParameters", "callback_name"},
+	{"StructureSymbols", "name"},
+	{"StructureMembers", "structure_name"},
+	{"StructurePointer", "structure_name"},
+	{"StructureFields", "structure_name"},
+	{"EnumSymbols", "name"},
+	{"EnumConstants", "enum_name"},
+	{"MacroSymbols", "name"},
+	{"MacroParameters", "macro_name"},
+	{"InterfaceSymbols", "name"},
+	{"InterfaceMethods", "interface_name"},
+	{"InterfaceMethodParameters", "interface_name || '::' || method_name"},
+	{"ClassSymbols", "name"},
+	{"ClassMembers", "class_name"},
+	{"ClassMethods", "class_name"},
+	{"ValueConstants", "owner_name"},
+	{"SymbolRequirementItems", "symbol_name"},
+	{"SymbolRequirements", "symbol_name"},
+	{"SymbolSections", "symbol_name"},
+	{"SymbolReferences", "source"},
+}
+
+// Deletes the records parsed from the pages marked in StaleSymbols and clears the marks, so the fill commands
+// parse those pages again. Returns the number of pages which were marked.
+func DropStaleRecords(ctx context.Context, conn *sql.DB) (int, error) {
+	if !tableExists(conn, "StaleSymbols") {
+		return 0, nil
+	}
+	var queries []string
+	for _, parsed := range parsedTables {
+		if tableExists(conn, parsed.table) {
+			queries = append(queries, fmt.Sprintf(`DELETE FROM %s WHERE %s IN (SELECT symbol_name FROM StaleSymbols);`, parsed.table, parsed.page))
+		}
+	}
+	tx, er := conn.BeginTx(ctx, nil)
+	if er != nil {
+		return 0, fmt.Errorf("cannot begin transaction for stale records: %w", er)
+	}
+	defer tx.Rollback()
+
+	var count int
+	if er := tx.QueryRowContext(ctx, `SELECT count(*) FROM StaleSymbols;`).Scan(&count); er != nil {
+		return 0, fmt.Errorf("cannot count StaleSymbols: %w", er)
+	}
+	if count == 0 {
+		return 0, nil
+	}
+	for _, query := range queries {
+		if _, er := tx.ExecContext(ctx, query); er != nil {
+			return 0, fmt.Errorf("cannot delete stale records: %w", er)
+		}
+	}
+	if _, er := tx.ExecContext(ctx, `DELETE FROM StaleSymbols;`); er != nil {
+		return 0, fmt.Errorf("cannot clear StaleSymbols: %w", er)
+	}
+	return count, tx.Commit()
+}
+
+// Adds the symbols to ScrapeJobs as pending, the jobs already present are made pending again
+func EnqueueScrapeJobs(ctx context.Context, conn *sql.DB, records []SymbolRecord) error {
+	if er := createTables(conn, scrapeJobSchema); er != nil {
+		return er
+	}
+	tx, er := conn.BeginTx(ctx, nil)
+	if er != nil {
+		return fmt.Errorf("cannot begin transaction for ScrapeJobs: %w", er)
+	}
+	defer tx.Rollback()
+
+	stmt, er := tx.PrepareContext(ctx, `INSERT INTO ScrapeJobs (symbol_name, url, state, updated_at) VALUES (?1, ?2, 'pending', ?3)
+		ON CONFLICT (symbol_name) DO UPDATE SET url = ?2, state = 'pending', error = NULL, updated_at = ?3;`)
+	if er != nil {
+		return fmt.Errorf("cannot prepare insert into ScrapeJobs: %w", er)
+	}
+	defer stmt.Close()
+	now := time.Now().UTC().Format(time.RFC3339)
+	for _, record := range records {
+		if _, er := stmt.ExecContext(ctx, record.Name, record.ScrapableUrl(), now); er != nil {
+			return fmt.Errorf("cannot add the job of %s: %w", record.Name, er)
+		}
+	}
+	return tx.Commit()
+}
+
+// Marks one pending job as in-flight and counts the attempt, ok is false when no job is pending
+func ClaimScrapeJob(ctx context.Context, conn *sql.DB) (request PageRequest, ok bool, er error) {
+	if er := createTables(conn, scrapeJobSchema); er != nil {
+		return request, false, er
+	}
+	er = conn.QueryRowContext(ctx, `UPDATE ScrapeJobs SET state = 'in-flight', attempts = attempts + 1, updated_at = ?
+		WHERE symbol_name = (SELECT symbol_name FROM ScrapeJobs WHERE state = 'pending' ORDER BY updated_at, symbol_name LIMIT 1)
+		RETURNING symbol_name, url;`, time.Now().UTC().Format(time.RFC3339)).Scan(&request.Name, &request.Url)
+	if er == sql.ErrNoRows {
+		return request, false, nil
+	} else if er != nil {
+		return request, false, fmt.Errorf("cannot claim a job from ScrapeJobs: %w", er)
+	}
+	return request, true, nil
+}
+
+// Stores the fetched page and marks its job done together, a record with Err marks the job failed instead.
+// Jobs stopped by the context are made pending again.
+func CompleteScrapeJob(ctx context.Context, conn *sql.DB, rec RawHTMLRecord) error {
+	if er := addColumns(conn, "RawHTML", rawHTMLColumns); er != nil {
+		return er
+	}
+	tx, er := conn.BeginTx(ctx, nil)
+	if er != nil {
+		return fmt.Errorf("cannot begin transaction for %s: %w", rec.SymbolName, er)
+	}
+	defer tx.Rollback()
+
+	if errors.Is(rec.Err, context.Canceled) || errors.Is(rec.Err, context.DeadlineExceeded) {
+		// Stopped before the page was fetched, so the job is left for resume without counting the attempt
+		_, er = tx.ExecContext(ctx, `UPDATE ScrapeJobs SET state = 'pending', attempts = max(attempts - 1, 0) WHERE symbol_name = ?;`, rec.SymbolName)
+		if er != nil {
+			return fmt.Errorf("cannot release the job of %s: %w", rec.SymbolName, er)
+		}
+		return tx.Commit()
+	}
+	state, cause := "done", sql.NullString{}
+	if rec.Err == nil {
+		rec.Err = insertRawHTML(tx, rec)
+	}
+	if rec.Err != nil {
+		state, cause = "failed", sql.NullString{String: rec.Err.Error(), Valid: true}
+	}
+	_, er = tx.ExecContext(ctx, `UPDATE ScrapeJobs SET state = ?, error = ?, updated_at = ? WHERE symbol_name = ?;`,
+		state, cause, time.Now().UTC().Format(time.RFC3339), rec.SymbolName)
+	if er != nil {
+		return fmt.Errorf("cannot update the job of %s: %w", rec.SymbolName, er)
+	}
+	return tx.Commit()
+}
+
+// Makes the jobs in the given states pending again, in-flight jobs are left by a scrape which was stopped
+func ResetScrapeJobs(ctx context.Context, conn *sql.DB, states ...string) (int64, error) {
+	if er := createTables(conn, scrapeJobSchema); er != nil {
+		return 0, er
+	}
+	encoded, er := json.Marshal(states)
+	if er != nil {
+		return 0, fmt.Errorf("cannot encode the states: %w", er)
+	}
+	result, er := conn.ExecContext(ctx, `UPDATE ScrapeJobs SET state = 'pending', updated_at = ?
+		WHERE state IN (SELECT value FROM json_each(?));`, time.Now().UTC().Format(time.RFC3339), string(encoded))
+	if er != nil {
+		return 0, fmt.Errorf("cannot reset the jobs: %w", er)
+	}
+	return result.RowsAffected()
+}
+
+// Number of jobs in each state
+func ScrapeJobCounts(conn *sql.DB) (map[string]int, error) {
+	counts := make(map[string]int)
+	if !tableExists(conn, "ScrapeJobs") {
+		return counts, nil
+	}
+	rows, er := conn.Query(`SELECT state, count(*) FROM ScrapeJobs GROUP BY state;`)
+	if er != nil {
+		return nil, fmt.Errorf("cannot count ScrapeJobs: %w", er)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			state string
+			count int
+		)
+		if er := rows.Scan(&state, &count); er != nil {
+			return nil, fmt.Errorf("cannot scan ScrapeJobs: %w", er)
+		}
+		counts[state] = count
+	}
+	return counts, rows.Err()
+}
+
+// Makes the claimed job pending again, for the job which is claimed but never given to the workers
+func ReleaseScrapeJob(ctx context.Context, conn *sql.DB, name string) error {
+	_, er := conn.ExecContext(ctx, `UPDATE ScrapeJobs SET state = 'pending', attempts = max(attempts - 1, 0) WHERE symbol_name = ?;`, name)
+	if er != nil {
+		return fmt.Errorf("cannot release the job of %s: %w", name, er)
+	}
+	return nil
+}
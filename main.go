@@ -3,16 +3,25 @@ package main
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"database/sql"
 	"fmt"
 	"io"
 	"log"
 	"os"
+	"os/signal"
 	"regexp"
 	"strings"
+	"syscall"
 
 	"github.com/cloakwiss/ntdocs/inter"
+	"github.com/cloakwiss/ntdocs/ntquery"
+	"github.com/cloakwiss/ntdocs/symbols/callback"
+	"github.com/cloakwiss/ntdocs/symbols/class"
+	"github.com/cloakwiss/ntdocs/symbols/cominterface"
+	"github.com/cloakwiss/ntdocs/symbols/enumeration"
 	"github.com/cloakwiss/ntdocs/symbols/function"
+	"github.com/cloakwiss/ntdocs/symbols/macro"
 	"github.com/cloakwiss/ntdocs/symbols/structure"
 	"github.com/cloakwiss/ntdocs/utils"
 	_ "github.com/mattn/go-sqlite3"
@@ -26,12 +35,42 @@ const (
 	SCRAPE_Structure Command = iota + 1
 	FILL_FunctionRecord
 	FILL_StructureRecord
+	FILL_EnumerationRecord
+	FILL_CallbackRecord
+	FILL_ReferenceRecord
+	FILL_MacroRecord
+	FILL_InterfaceRecord
+	SCRAPE_InterfaceMethod
+	FILL_ClassRecord
+	SCRAPE_Closure
+	SCRAPE_Seed
+	SCRAPE_Symbol
+	DISCOVER_Headers
+	REFRESH_Pages
+	RESUME_ScrapeJobs
+	RETRY_FailedJobs
+	SERVE_Fixtures
 )
 
 var usageHint = []struct{ name, description string }{
 	{"scrape-structure", "Scrape only the structs which are required"},
 	{"fill-function-record", "Read scraped data and fill the Function"},
 	{"fill-structure-record", "Read scraped data and fill the Structure Table"},
+	{"fill-enumeration-record", "Read scraped data and fill the Enumeration Tables"},
+	{"fill-callback-record", "Read scraped data and fill the Callback Tables"},
+	{"fill-reference-record", "Read scraped data and fill the Remarks, See also and References Tables"},
+	{"fill-macro-record", "Read scraped data and fill the Macro Tables"},
+	{"fill-interface-record", "Read scraped data and fill the COM Interface Tables, methods are filled when their pages are scraped"},
+	{"scrape-interface-method", "Scrape the pages of methods of the filled interfaces"},
+	{"fill-class-record", "Read scraped data and fill the C++ Class Tables"},
+	{"scrape-closure", "Scrape and fill the types referenced by filled records, repeated till no new type is found"},
+	{"scrape-seed", "Scrape only the given headers, DLLs or symbols, see --scrape-seed -help for the arguments"},
+	{"scrape", "Scrape the symbols of given types or matching an SQL filter, see --scrape -help for the arguments"},
+	{"discover-headers", "Fetch the TOC of headers which are not fetched yet and fill the Symbol table from them"},
+	{"refresh", "Fetch the scraped pages again and mark the records of changed ones to be filled again, see --refresh -help for the arguments"},
+	{"resume", "Continue the scrape jobs left by a stopped scrape"},
+	{"retry-failed", "Scrape the failed jobs again along with the ones left by a stopped scrape"},
+	{"serve-fixtures", "Serve pages from a directory or the database over local HTTP, see --serve-fixtures -help for the arguments"},
 }
 
 func matchFlag(flag string) (Command, bool) {
@@ -57,6 +96,7 @@ func usage(out io.Writer) {
 		k, v := usageHint[i].name, usageHint[i].description
 		fmt.Fprintf(out, "\t--%s\t\t%s\n", k, v)
 	}
+	fmt.Fprintln(out, "Pages are fetched from NTDOCS_BASE_URL when it is set i.e. http://127.0.0.1:8080/en-us of --serve-fixtures")
 }
 
 func main() {
@@ -66,7 +106,7 @@ func main() {
 	out := bufio.NewWriter(stdout)
 	defer out.Flush()
 
-	if len(os.Args) != 2 {
+	if len(os.Args) < 2 {
 		fmt.Fprintln(out, "Need only 1 flag.")
 		usage(out)
 		return
@@ -86,31 +126,106 @@ func main() {
 		usage(out)
 		return
 	}
+	if len(args) > 1 && !takesArguments[cmd] {
+		fmt.Fprintln(out, "Need only 1 flag.")
+		usage(out)
+		return
+	}
+
+	if baseUrl := os.Getenv("NTDOCS_BASE_URL"); baseUrl != "" {
+		if er := utils.SetSourceUrl(baseUrl); er != nil {
+			fmt.Fprintln(out, er)
+			return
+		}
+	}
+
+	// First signal stops the command at the next safe point, second one exits at once
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		stop()
+		log.Println("Stopping, interrupt again to exit at once")
+	}()
 
-	run(cmd, out)
+	run(ctx, cmd, out, args[1:])
 }
 
-func run(cmd Command, stdout *bufio.Writer) {
+// Commands which read the arguments after the flag
+var takesArguments = map[Command]bool{
+	SCRAPE_Seed:       true,
+	SCRAPE_Symbol:     true,
+	REFRESH_Pages:     true,
+	RESUME_ScrapeJobs: true,
+	RETRY_FailedJobs:  true,
+	SERVE_Fixtures:    true,
+}
+
+func run(ctx context.Context, cmd Command, stdout *bufio.Writer, args []string) {
 	db, closer := inter.OpenDB()
 	defer closer()
+	fetcher := inter.NewFetcher(inter.DefaultFetcherConfig)
+
+	// Records of the pages changed by refresh are filled again
+	if strings.HasPrefix(usageHint[cmd-1].name, "fill-") {
+		dropped, er := inter.DropStaleRecords(ctx, db)
+		if er != nil {
+			log.Panicln(er)
+		}
+		if dropped > 0 {
+			fmt.Fprintln(stdout, dropped, "stale pages will be filled again")
+		}
+	}
 
 	log.SetFlags(log.Llongfile)
 
 	switch cmd {
 	case SCRAPE_Structure:
-		scrapeStructureRecords(db, stdout)
+		scrapeStructureRecords(ctx, db, fetcher, stdout)
 	case FILL_FunctionRecord:
-		fillFunctionRecords(db, stdout)
+		fillFunctionRecords(ctx, db, stdout)
 	case FILL_StructureRecord:
-		fillStructureRecords(db, stdout)
+		fillStructureRecords(ctx, db, stdout)
+	case FILL_EnumerationRecord:
+		fillEnumerationRecords(ctx, db, stdout)
+	case FILL_CallbackRecord:
+		fillCallbackRecords(ctx, db, stdout)
+	case FILL_ReferenceRecord:
+		fillReferenceRecords(ctx, db, stdout)
+	case FILL_MacroRecord:
+		fillMacroRecords(ctx, db, stdout)
+	case FILL_InterfaceRecord:
+		fillInterfaceRecords(ctx, db, stdout)
+	case SCRAPE_InterfaceMethod:
+		scrapeInterfaceMethods(ctx, db, fetcher, stdout)
+	case FILL_ClassRecord:
+		fillClassRecords(ctx, db, stdout)
+	case SCRAPE_Closure:
+		scrapeClosure(ctx, db, fetcher, stdout)
+	case SCRAPE_Seed:
+		scrapeSeeds(ctx, db, stdout, args)
+	case SCRAPE_Symbol:
+		scrapeSymbols(ctx, db, stdout, args)
+	case DISCOVER_Headers:
+		discoverHeaders(ctx, db, fetcher, stdout)
+	case REFRESH_Pages:
+		refreshPages(ctx, db, stdout, args)
+	case RESUME_ScrapeJobs:
+		resumeScrapeJobs(ctx, db, stdout, args, false)
+	case RETRY_FailedJobs:
+		resumeScrapeJobs(ctx, db, stdout, args, true)
+	case SERVE_Fixtures:
+		serveFixtures(ctx, db, stdout, args)
 	default:
 		log.Fatal("Some unknown command found")
 
 	}
 }
 
-func fillStructureRecords(db *sql.DB, stdoutbuf *bufio.Writer) {
-	resultRows, er := db.Query("SELECT * FROM RawHTML;")
+func fillStructureRecords(ctx context.Context, db *sql.DB, stdoutbuf *bufio.Writer) {
+	// Path of the page is needed to resolve the relative links
+	resultRows, er := db.Query(`SELECT RawHTML.symbolName, RawHTML.html, ifnull(Symbol.url, '/') FROM RawHTML
+		LEFT JOIN Symbol ON Symbol.name = RawHTML.symbolName GROUP BY RawHTML.symbolName;`)
 	if er != nil {
 		log.Panicf("Failed to query RawHTML table: %s\n", er)
 	}
@@ -119,24 +234,22 @@ func fillStructureRecords(db *sql.DB, stdoutbuf *bufio.Writer) {
 	if er != nil {
 		log.Panicln("Failed to compile regex: ", er)
 	}
-	pattr, er := regexp.Compile(".*union.*")
-	if er != nil {
-		log.Panicln("Failed to compile regex: ", er)
-	}
 
 	parser := tree_sitter.NewParser()
 	defer parser.Close()
 	parser.SetLanguage(tree_sitter.NewLanguage(tree_sitter_c.Language()))
 
 	var (
-		l, p, all  int
-		data, name string
-		structures = make([]structure.StructDeclaration, 0, 80)
+		p, all           int
+		data, name, path string
+		structures       = make([]structure.StructDeclaration, 0, 80)
+		// Structures are inserted without replacing, so the filled ones are skipped
+		filled = inter.FilledNames(db, "StructureSymbols", "name")
 	)
-	for resultRows.Next() {
-		resultRows.Scan(&name, &data)
+	for ctx.Err() == nil && resultRows.Next() {
+		resultRows.Scan(&name, &data, &path)
 
-		if found := pattern.MatchString(name); found {
+		if found := pattern.MatchString(name); found && !filled[name] {
 			func() {
 				decompressed, er := inter.GetDecompressed(data)
 				if er != nil {
@@ -151,53 +264,619 @@ func fillStructureRecords(db *sql.DB, stdoutbuf *bufio.Writer) {
 				if len(content["syntax"]) == 1 {
 					blk := content["syntax"][0]
 					code := []byte(blk.Text())
-					found := pattr.Match([]byte(code))
 
-					if found {
-						l += 1
-					} else {
-						tree := parser.Parse(code, nil)
-						data, er := structure.HandleSyntaxSection(tree, code)
-						if er == nil {
-							p += 1
-							structures = append(structures, data)
+					tree := parser.Parse(code, nil)
+					data, er := structure.HandleSyntaxSection(tree, code)
+					if er == nil {
+						if data.MemberConstants, er = function.HandleParameterConstants(content["members"]); er != nil {
+							log.Println("Constants not found: ", name, ": ", er)
+						}
+						if data.MemberDocumentation, er = function.HandleMemberSection(content["members"], path); er != nil {
+							log.Println("Members not found: ", name, ": ", er)
 						}
-						tree.Close()
+						data.Description = utils.Document(content["basic-description"], path)
+						if data.ParsedRequirements, er = utils.HandleRequirementsSection(content["requirements"]); er != nil {
+							log.Println("Requirements not found: ", name)
+						}
+						data.Requirements = data.ParsedRequirements.String()
+						p += 1
+						structures = append(structures, data)
+					} else {
+						log.Println("Left: ", name, ": ", er)
 					}
+					tree.Close()
 					all += 1
 				}
 				fmt.Fprintln(stdoutbuf)
 			}()
 		}
 	}
+	// Not read till the end when stopped
+	if er := resultRows.Close(); er != nil {
+		log.Panicln("Cannot close Connection")
+	}
 	if er := inter.AddToStructSymbol(db, structures, stdoutbuf); er != nil {
 		log.Fatal(er.Error())
 	}
-	fmt.Fprintln(stdoutbuf, l, "/", all)
 	fmt.Fprintln(stdoutbuf, p, "/", all)
 }
 
-func scrapeStructureRecords(db *sql.DB, stdoutbuf *bufio.Writer) {
+func fillEnumerationRecords(ctx context.Context, db *sql.DB, stdoutbuf *bufio.Writer) {
+	resultRows, er := db.Query(`SELECT RawHTML.symbolName, RawHTML.html FROM RawHTML
+		JOIN Symbol ON Symbol.name = RawHTML.symbolName WHERE Symbol.type IS 'enumeration';`)
+	if er != nil {
+		log.Panicf("Failed to query RawHTML table: %s\n", er)
+	}
+
+	parser := tree_sitter.NewParser()
+	defer parser.Close()
+	parser.SetLanguage(tree_sitter.NewLanguage(tree_sitter_c.Language()))
+
+	var (
+		p, all       int
+		data, name   string
+		enumerations = make([]enumeration.EnumDeclaration, 0, 80)
+	)
+	for ctx.Err() == nil && resultRows.Next() {
+		resultRows.Scan(&name, &data)
+
+		decompressed, er := inter.GetDecompressed(data)
+		if er != nil {
+			log.Panicf("Failed to scan rows: %s\n", er)
+		}
+
+		backing := bytes.NewBuffer(decompressed)
+		buffer := bufio.NewReader(backing)
+		mainContent := utils.GetMainContent(buffer)
+		content := utils.GetAllSection(mainContent)
+
+		all += 1
+		if len(content["syntax"]) != 1 {
+			log.Println("Left: ", name)
+			continue
+		}
+		code := []byte(content["syntax"][0].Text())
+		tree := parser.Parse(code, nil)
+		decl, er := enumeration.HandleSyntaxSection(tree, code)
+		tree.Close()
+		if er != nil {
+			log.Println("Left: ", name, ": ", er)
+			continue
+		}
+		if er := enumeration.HandleConstantsSection(content["constants"], &decl); er != nil {
+			log.Println("Constants not documented: ", name)
+		}
+		decl.Description = utils.JoinBlocks(content["basic-description"])
+		enumerations = append(enumerations, decl)
+		p += 1
+	}
+	if er := resultRows.Close(); er != nil {
+		log.Panicln("Cannot close Connection")
+	}
+	if er := inter.AddToEnumSymbol(db, enumerations); er != nil {
+		log.Fatal(er.Error())
+	}
+	fmt.Fprintln(stdoutbuf, p, "/", all)
+}
+
+func fillCallbackRecords(ctx context.Context, db *sql.DB, stdoutbuf *bufio.Writer) {
+	resultRows, er := db.Query(`SELECT RawHTML.symbolName, RawHTML.html, Symbol.url FROM RawHTML
+		JOIN Symbol ON Symbol.name = RawHTML.symbolName WHERE Symbol.type LIKE 'callback%';`)
+	if er != nil {
+		log.Panicf("Failed to query RawHTML table: %s\n", er)
+	}
+
+	parser := tree_sitter.NewParser()
+	defer parser.Close()
+	parser.SetLanguage(tree_sitter.NewLanguage(tree_sitter_c.Language()))
+
+	var (
+		p, all           int
+		data, name, path string
+		callbacks        = make([]callback.CallbackDeclarationForInsertion, 0, 80)
+	)
+	for ctx.Err() == nil && resultRows.Next() {
+		resultRows.Scan(&name, &data, &path)
+
+		decompressed, er := inter.GetDecompressed(data)
+		if er != nil {
+			log.Panicf("Failed to scan rows: %s\n", er)
+		}
+
+		backing := bytes.NewBuffer(decompressed)
+		buffer := bufio.NewReader(backing)
+		mainContent := utils.GetMainContent(buffer)
+		content := utils.GetAllSection(mainContent)
+
+		all += 1
+		if len(content["syntax"]) != 1 {
+			log.Println("Left: ", name)
+			continue
+		}
+		sig, er := callback.HandleSyntaxSection(parser, content["syntax"][0].Text())
+		if er != nil {
+			log.Println("Left: ", name, ": ", er)
+			continue
+		}
+		var (
+			paras     utils.AssociativeArray[string, []string]
+			constants utils.AssociativeArray[string, []utils.ValueDefinition]
+			rendered  []utils.Rendered
+		)
+		if sig.Arity > 0 {
+			paras, er = function.HandleParameterSectionOfFunction(content["parameters"])
+			if er != nil {
+				log.Println("Left: ", sig, ": ", er)
+				continue
+			}
+			rendered, _ = function.RenderParameterSection(content["parameters"], path)
+			if constants, er = function.HandleParameterConstants(content["parameters"]); er != nil {
+				log.Println("Constants not found: ", sig, ": ", er)
+			}
+			if len(paras) != int(sig.Arity) {
+				log.Println("Parameter parse failed by ", int(sig.Arity)-len(paras), ": ", sig)
+				continue
+			}
+			function.LinkSizeParameters(sig.Parameters, paras)
+		}
+		req, er := utils.HandleRequirementsSection(content["requirements"])
+		if er != nil {
+			log.Println("Requirements not found: ", sig)
+		}
+		callbacks = append(callbacks, callback.CallbackDeclarationForInsertion{
+			CallbackDeclaration:  sig,
+			ParameterDescription: paras,
+			ParameterConstants:   constants,
+			Description:          utils.JoinBlocks(content["basic-description"]),
+			Requirements:         req.String(),
+			ParsedRequirements:   req,
+			RenderedDescription:  utils.RenderBlocks(content["basic-description"], path),
+			RenderedParameters:   rendered,
+		})
+		p += 1
+	}
+	if er := resultRows.Close(); er != nil {
+		log.Panicln("Cannot close Connection")
+	}
+	for _, declar := range callbacks {
+		if ctx.Err() != nil {
+			break
+		}
+		if er := inter.AddToCallbackSymbol(db, declar); er != nil {
+			log.Panicln("Some error in db: ", er)
+		}
+	}
+	fmt.Fprintln(stdoutbuf, p, "/", all)
+}
+
+// Macro pages are not always marked in Symbol table, so the title of every function page is checked
+func fillMacroRecords(ctx context.Context, db *sql.DB, stdoutbuf *bufio.Writer) {
+	resultRows, er := db.Query(`SELECT RawHTML.symbolName, RawHTML.html, ifnull(Symbol.url, '/'), ifnull(Symbol.type, '') FROM RawHTML
+		LEFT JOIN Symbol ON Symbol.name = RawHTML.symbolName GROUP BY RawHTML.symbolName;`)
+	if er != nil {
+		log.Panicf("Failed to query RawHTML table: %s\n", er)
+	}
+
+	parser := tree_sitter.NewParser()
+	defer parser.Close()
+	parser.SetLanguage(tree_sitter.NewLanguage(tree_sitter_c.Language()))
+
+	var (
+		p, all                       int
+		data, name, path, symbolType string
+		macros                       = make([]macro.MacroDeclarationForInsertion, 0, 80)
+	)
+	for ctx.Err() == nil && resultRows.Next() {
+		resultRows.Scan(&name, &data, &path, &symbolType)
+		if symbolType != "macro" && symbolType != "function" && symbolType != "" {
+			continue
+		}
+
+		decompressed, er := inter.GetDecompressed(data)
+		if er != nil {
+			log.Panicf("Failed to scan rows: %s\n", er)
+		}
+
+		backing := bytes.NewBuffer(decompressed)
+		buffer := bufio.NewReader(backing)
+		mainContent := utils.GetMainContent(buffer)
+		if symbolType != "macro" && !macro.IsMacroPage(mainContent) {
+			continue
+		}
+		content := utils.GetAllSection(mainContent)
+
+		all += 1
+		if len(content["syntax"]) != 1 {
+			log.Println("Left: ", name)
+			continue
+		}
+		decl, er := macro.HandleSyntaxSection(parser, content["syntax"][0].Text())
+		if er != nil {
+			log.Println("Left: ", name, ": ", er)
+			continue
+		}
+		var (
+			paras    utils.AssociativeArray[string, []string]
+			rendered []utils.Rendered
+		)
+		if decl.Signature != nil && decl.Signature.Arity > 0 {
+			if paras, er = function.HandleParameterSectionOfFunction(content["parameters"]); er != nil {
+				log.Println("Parameters not found: ", name, ": ", er)
+			}
+			rendered, _ = function.RenderParameterSection(content["parameters"], path)
+		}
+		req, er := utils.HandleRequirementsSection(content["requirements"])
+		if er != nil {
+			log.Println("Requirements not found: ", name)
+		}
+		macros = append(macros, macro.MacroDeclarationForInsertion{
+			MacroDeclaration:     decl,
+			Description:          utils.JoinBlocks(content["basic-description"]),
+			Requirements:         req.String(),
+			ParsedRequirements:   req,
+			ParameterDescription: paras,
+			RenderedDescription:  utils.RenderBlocks(content["basic-description"], path),
+			RenderedParameters:   rendered,
+		})
+		p += 1
+	}
+	if er := resultRows.Close(); er != nil {
+		log.Panicln("Cannot close Connection")
+	}
+	for _, declar := range macros {
+		if ctx.Err() != nil {
+			break
+		}
+		if er := inter.AddToMacroSymbol(db, declar); er != nil {
+			log.Panicln("Some error in db: ", er)
+		}
+	}
+	fmt.Fprintln(stdoutbuf, p, "/", all)
+}
+
+func fillReferenceRecords(ctx context.Context, db *sql.DB, stdoutbuf *bufio.Writer) {
+	paths, er := inter.SymbolPaths(db)
+	if er != nil {
+		log.Panicln(er)
+	}
+
+	resultRows, er := db.Query(`SELECT RawHTML.symbolName, Symbol.url, RawHTML.html FROM RawHTML
+		JOIN Symbol ON Symbol.name = RawHTML.symbolName GROUP BY RawHTML.symbolName;`)
+	if er != nil {
+		log.Panicf("Failed to query RawHTML table: %s\n", er)
+	}
+
+	// Only the compressed html is kept till the rows are closed
+	type page struct{ name, path, data string }
+	var pages = make([]page, 0, 80)
+	for ctx.Err() == nil && resultRows.Next() {
+		var p page
+		resultRows.Scan(&p.name, &p.path, &p.data)
+		pages = append(pages, p)
+	}
+	if er := resultRows.Close(); er != nil {
+		log.Panicln("Cannot close Connection")
+	}
+	for _, p := range pages {
+		if ctx.Err() != nil {
+			break
+		}
+		decompressed, er := inter.GetDecompressed(p.data)
+		if er != nil {
+			log.Panicf("Failed to scan rows: %s\n", er)
+		}
+		buffer := bufio.NewReader(bytes.NewBuffer(decompressed))
+		content := utils.GetAllSection(utils.GetMainContent(buffer))
+		if er := inter.AddToReferences(db, p.name, p.path, content, paths); er != nil {
+			log.Panicln("Some error in db: ", er)
+		}
+	}
+	fmt.Fprintln(stdoutbuf, len(pages), "pages")
+}
+
+func fillInterfaceRecords(ctx context.Context, db *sql.DB, stdoutbuf *bufio.Writer) {
+	resultRows, er := db.Query(`SELECT RawHTML.symbolName, RawHTML.html, Symbol.url FROM RawHTML
+		JOIN Symbol ON Symbol.name = RawHTML.symbolName WHERE Symbol.type IS 'interface';`)
+	if er != nil {
+		log.Panicf("Failed to query RawHTML table: %s\n", er)
+	}
+
+	var (
+		p, all           int
+		data, name, path string
+		interfaces       = make([]cominterface.InterfaceDeclarationForInsertion, 0, 80)
+	)
+	for ctx.Err() == nil && resultRows.Next() {
+		resultRows.Scan(&name, &data, &path)
+
+		decompressed, er := inter.GetDecompressed(data)
+		if er != nil {
+			log.Panicf("Failed to scan rows: %s\n", er)
+		}
+		buffer := bufio.NewReader(bytes.NewBuffer(decompressed))
+		content := utils.GetAllSection(utils.GetMainContent(buffer))
+
+		all += 1
+		decl, er := cominterface.HandleInterfacePage(name, path, content)
+		if er != nil {
+			log.Println("Left: ", name, ": ", er)
+			continue
+		}
+		req, er := utils.HandleRequirementsSection(content["requirements"])
+		if er != nil {
+			log.Println("Requirements not found: ", name)
+		}
+		interfaces = append(interfaces, cominterface.InterfaceDeclarationForInsertion{
+			InterfaceDeclaration: decl,
+			Description:          utils.JoinBlocks(content["basic-description"]),
+			Requirements:         req.String(),
+			ParsedRequirements:   req,
+			RenderedDescription:  utils.RenderBlocks(content["basic-description"], path),
+		})
+		p += 1
+	}
+	if er := resultRows.Close(); er != nil {
+		log.Panicln("Cannot close Connection")
+	}
+	for _, declar := range interfaces {
+		if ctx.Err() != nil {
+			break
+		}
+		if er := inter.AddToInterfaceSymbol(db, declar); er != nil {
+			log.Panicln("Some error in db: ", er)
+		}
+	}
+	fmt.Fprintln(stdoutbuf, p, "/", all, "interfaces")
+	// Tables are only created with the first interface
+	if len(interfaces) > 0 {
+		fillInterfaceMethodRecords(ctx, db, stdoutbuf)
+	}
+}
+
+// Method pages are stored in RawHTML as `IClassFactory::CreateInstance` by scrape-interface-method
+func fillInterfaceMethodRecords(ctx context.Context, db *sql.DB, stdoutbuf *bufio.Writer) {
+	resultRows, er := db.Query(`SELECT RawHTML.symbolName, RawHTML.html, InterfaceMethods.target_path FROM RawHTML
+		JOIN InterfaceMethods ON InterfaceMethods.interface_name || '::' || InterfaceMethods.name = RawHTML.symbolName;`)
+	if er != nil {
+		log.Panicf("Failed to query RawHTML table: %s\n", er)
+	}
+
+	parser := tree_sitter.NewParser()
+	defer parser.Close()
+	parser.SetLanguage(tree_sitter.NewLanguage(tree_sitter_c.Language()))
+
+	var (
+		p, all           int
+		data, name, path string
+		methods          = make([]cominterface.MethodDeclarationForInsertion, 0, 80)
+	)
+	for ctx.Err() == nil && resultRows.Next() {
+		resultRows.Scan(&name, &data, &path)
+
+		decompressed, er := inter.GetDecompressed(data)
+		if er != nil {
+			log.Panicf("Failed to scan rows: %s\n", er)
+		}
+		buffer := bufio.NewReader(bytes.NewBuffer(decompressed))
+		content := utils.GetAllSection(utils.GetMainContent(buffer))
+
+		all += 1
+		interfaceName, methodName, _ := cominterface.SplitMethodName(name)
+		if len(content["syntax"]) != 1 {
+			log.Println("Left: ", name)
+			continue
+		}
+		sig, er := cominterface.HandleMethodSyntax(parser, interfaceName, content["syntax"][0].Text())
+		if er != nil {
+			log.Println("Left: ", name, ": ", er)
+			continue
+		}
+		// Name in the methods table is the one used for the record
+		sig.Name = methodName
+		var (
+			paras    utils.AssociativeArray[string, []string]
+			rendered []utils.Rendered
+		)
+		if sig.Arity > 0 {
+			if paras, er = function.HandleParameterSectionOfFunction(content["parameters"]); er != nil {
+				log.Println("Parameters not found: ", name, ": ", er)
+			}
+			function.LinkSizeParameters(sig.Parameters, paras)
+			rendered, _ = function.RenderParameterSection(content["parameters"], path)
+		}
+		methods = append(methods, cominterface.MethodDeclarationForInsertion{
+			MethodDeclaration:    sig,
+			Description:          utils.JoinBlocks(content["basic-description"]),
+			ParameterDescription: paras,
+			RenderedParameters:   rendered,
+			ReturnValue:          function.HandleReturnValueSectionOfFunction(content["return-value"], sig.ReturnType),
+		})
+		p += 1
+	}
+	if er := resultRows.Close(); er != nil {
+		log.Panicln("Cannot close Connection")
+	}
+	for _, declar := range methods {
+		if ctx.Err() != nil {
+			break
+		}
+		if er := inter.AddToInterfaceMethod(db, declar); er != nil {
+			log.Panicln("Some error in db: ", er)
+		}
+	}
+	fmt.Fprintln(stdoutbuf, p, "/", all, "methods")
+}
+
+// Class pages are `nl-` pages like `nl-gdiplusimaging-bitmapdata`
+func fillClassRecords(ctx context.Context, db *sql.DB, stdoutbuf *bufio.Writer) {
+	resultRows, er := db.Query(`SELECT RawHTML.symbolName, RawHTML.html, Symbol.url FROM RawHTML
+		JOIN Symbol ON Symbol.name = RawHTML.symbolName WHERE Symbol.type IS 'class' OR Symbol.url LIKE '%/nl-%';`)
+	if er != nil {
+		log.Panicf("Failed to query RawHTML table: %s\n", er)
+	}
+
+	parser := tree_sitter.NewParser()
+	defer parser.Close()
+	parser.SetLanguage(tree_sitter.NewLanguage(tree_sitter_c.Language()))
+
+	var (
+		p, all           int
+		data, name, path string
+		classes          = make([]class.ClassDeclarationForInsertion, 0, 80)
+	)
+	for ctx.Err() == nil && resultRows.Next() {
+		resultRows.Scan(&name, &data, &path)
+
+		decompressed, er := inter.GetDecompressed(data)
+		if er != nil {
+			log.Panicf("Failed to scan rows: %s\n", er)
+		}
+		buffer := bufio.NewReader(bytes.NewBuffer(decompressed))
+		content := utils.GetAllSection(utils.GetMainContent(buffer))
+
+		all += 1
+		decl, er := class.HandleClassPage(parser, name, path, content)
+		if er != nil {
+			log.Println("Left: ", name, ": ", er)
+			continue
+		}
+		req, er := utils.HandleRequirementsSection(content["requirements"])
+		if er != nil {
+			log.Println("Requirements not found: ", name)
+		}
+		classes = append(classes, class.ClassDeclarationForInsertion{
+			ClassDeclaration:    decl,
+			Description:         utils.JoinBlocks(content["basic-description"]),
+			Requirements:        req.String(),
+			ParsedRequirements:  req,
+			RenderedDescription: utils.RenderBlocks(content["basic-description"], path),
+		})
+		p += 1
+	}
+	if er := resultRows.Close(); er != nil {
+		log.Panicln("Cannot close Connection")
+	}
+	for _, declar := range classes {
+		if ctx.Err() != nil {
+			break
+		}
+		if er := inter.AddToClassSymbol(db, declar); er != nil {
+			log.Panicln("Some error in db: ", er)
+		}
+	}
+	fmt.Fprintln(stdoutbuf, p, "/", all, "classes")
+}
+
+func scrapeInterfaceMethods(ctx context.Context, db *sql.DB, fetcher *inter.Fetcher, stdoutbuf *bufio.Writer) {
 	_ = stdoutbuf
-	list := inter.RunQuery(db, structure.Query)
-	rawHtml := make(chan inter.RawHTMLRecord)
-	go inter.ReqWorkers(list, rawHtml)
-	for rec := range rawHtml {
-		inter.AddToRawHTML(db, rec)
+	list, er := inter.InterfaceMethodRecords(db)
+	if er != nil {
+		log.Panicln(er)
 	}
+	scrapeRecords(ctx, db, fetcher, list)
 }
 
-func fillFunctionRecords(db *sql.DB, stdoutbuf *bufio.Writer) {
+// Each round scrapes the types referenced by parameters, return types and members which cannot be resolved yet,
+// and fills them so that their members are referenced in the next round
+func scrapeClosure(ctx context.Context, db *sql.DB, fetcher *inter.Fetcher, stdoutbuf *bufio.Writer) {
+	attempted := make(map[string]bool)
+	for round := 1; ctx.Err() == nil; round += 1 {
+		list := closureRecords(db, attempted)
+		fmt.Fprintln(stdoutbuf, "Round", round, ":", len(list), "types")
+		stdoutbuf.Flush()
+		if len(list) == 0 {
+			break
+		}
+		scrapeRecords(ctx, db, fetcher, list)
+		fillStructureRecords(ctx, db, stdoutbuf)
+		fillEnumerationRecords(ctx, db, stdoutbuf)
+		fillCallbackRecords(ctx, db, stdoutbuf)
+	}
+}
+
+// Pages of the unresolved types, a symbol is only tried once so that a page which cannot be filled does not repeat
+func closureRecords(db *sql.DB, attempted map[string]bool) []inter.SymbolRecord {
+	var (
+		search = ntquery.NewSearch(db, 0)
+		names  []string
+		seen   = make(map[string]bool)
+	)
+	for _, expression := range inter.ReferencedTypes(db) {
+		for _, name := range search.ResolveType(expression).Unresolved() {
+			var candidates []string
+			if fields := strings.Fields(name); len(fields) > 0 {
+				name = fields[len(fields)-1]
+				// Symbol table has the typedef names, tag like `_FOO` or `tagFOO` is declared in the page of `FOO`
+				if len(fields) > 1 {
+					if typedef := typedefOfTag(name); typedef != name {
+						candidates = append(candidates, typedef)
+					}
+				}
+			}
+			// Pointer typedefs are declared in the page of the structure
+			candidates = append(candidates, name)
+			if after, found := strings.CutPrefix(name, "LP"); found {
+				candidates = append(candidates, after)
+			} else if after, found := strings.CutPrefix(name, "P"); found {
+				candidates = append(candidates, after)
+			}
+			for _, candidate := range candidates {
+				if !seen[candidate] && !attempted[candidate] {
+					seen[candidate] = true
+					names = append(names, candidate)
+				}
+			}
+		}
+	}
+	list := inter.UnscrapedSymbols(db, names)
+	for _, record := range list {
+		attempted[record.Name] = true
+	}
+	return list
+}
+
+// Typedef name which is conventionally given to the tag, tag itself when it follows no convention
+func typedefOfTag(tag string) string {
+	for _, prefix := range []string{"tag_", "tag", "_"} {
+		if after, found := strings.CutPrefix(tag, prefix); found && after != "" {
+			return after
+		}
+	}
+	return tag
+}
+
+func scrapeStructureRecords(ctx context.Context, db *sql.DB, fetcher *inter.Fetcher, stdoutbuf *bufio.Writer) {
+	_ = stdoutbuf
+	list := inter.RunQuery(db, structure.Query)
+	scrapeRecords(ctx, db, fetcher, list)
+}
+
+func fillFunctionRecords(ctx context.Context, db *sql.DB, stdoutbuf *bufio.Writer) {
 	_ = stdoutbuf
-	resultRows, er := db.Query("SELECT symbolName, html FROM RawHTML;")
+	// Path of the page is needed to resolve the relative links
+	// Only function pages, the placeholder function in the syntax of callbacks would be taken as an export
+	resultRows, er := db.Query(`SELECT RawHTML.symbolName, RawHTML.html, ifnull(Symbol.url, '/') FROM RawHTML
+		JOIN Symbol ON Symbol.name = RawHTML.symbolName WHERE Symbol.type IS 'function' GROUP BY RawHTML.symbolName;`)
 	if er != nil {
 		log.Panicf("Failed to query RawHTML table: %s\n", er)
 	}
-	var data, name string
+	var (
+		data, name, path string
+		// Parameters are inserted without replacing, so the filled ones are skipped
+		filled = inter.FilledNames(db, "FunctionSymbols", "name")
+	)
 
-	for resultRows.Next() {
-		resultRows.Scan(&name, &data)
+	parser := tree_sitter.NewParser()
+	defer parser.Close()
+	parser.SetLanguage(tree_sitter.NewLanguage(tree_sitter_c.Language()))
+
+	for ctx.Err() == nil && resultRows.Next() {
+		resultRows.Scan(&name, &data, &path)
 		// fmt.Fprintf(buf, "%s: %s\n", name, data)
+		// Pages of interface methods are filled by fill-interface-record
+		if strings.Contains(name, "::") || filled[name] {
+			continue
+		}
 		func() {
 			decompressed, er := inter.GetDecompressed(data)
 			if er != nil {
@@ -208,10 +887,24 @@ func fillFunctionRecords(db *sql.DB, stdoutbuf *bufio.Writer) {
 			backing := bytes.NewBuffer(decompressed)
 			buffer := bufio.NewReader(backing)
 			mainContent := utils.GetMainContent(buffer)
+			// Macros have the same prefix in url, but they are filled by fill-macro-record
+			if macro.IsMacroPage(mainContent) {
+				log.Println("Macro: ", name)
+				return
+			}
 			content := utils.GetAllSection(mainContent)
-			sig := function.HandleFunctionDeclarationSectionOfFunction(content["syntax"])
+			sig, er := function.HandleFunctionDeclarationSectionOfFunction(parser, content["syntax"])
+			if er != nil {
+				log.Println("Left: ", name, ": ", er)
+				return
+			}
+			var (
+				paras     utils.AssociativeArray[string, []string]
+				constants utils.AssociativeArray[string, []utils.ValueDefinition]
+				rendered  []utils.Rendered
+			)
 			if sig.Arity > 0 {
-				paras, er := function.HandleParameterSectionOfFunction(content["parameters"])
+				paras, er = function.HandleParameterSectionOfFunction(content["parameters"])
 				if len(paras) != int(sig.Arity) {
 					log.Println("Parameter parse failed by ", int(sig.Arity)-len(paras), ": ", sig)
 					return
@@ -223,28 +916,37 @@ func fillFunctionRecords(db *sql.DB, stdoutbuf *bufio.Writer) {
 				if er != nil {
 					log.Panicln(er)
 				}
-				req, er := utils.HandleRequriementSectionOfFunction(content["requirements"])
-				if er != nil {
-					if er == utils.ErrNotSingleElement {
-						log.Println("Left: ", sig)
-						return
-					} else {
-						log.Panicf("Requirements genearation of %+v failed due to: %s\n", sig, er)
-					}
+				function.LinkSizeParameters(sig.Parameters, paras)
+				if constants, er = function.HandleParameterConstants(content["parameters"]); er != nil {
+					log.Println("Constants not found: ", sig, ": ", er)
 				}
-				declar := function.FunctionDeclarationForInsertion{
-					FunctionDeclaration:  sig,
-					ParameterDescription: paras,
-					Description:          utils.JoinBlocks(content["basic-description"]),
-					Requirements:         req,
-				}
-				_ = declar
-				if er := inter.AddToFunctionSymbol(db, declar); er != nil {
-					log.Panicln("Some error in db: ", er)
+				rendered, _ = function.RenderParameterSection(content["parameters"], path)
+			}
+			req, er := utils.HandleRequirementsSection(content["requirements"])
+			if er != nil {
+				if er == utils.ErrNotSingleElement {
+					log.Println("Left: ", sig)
+					return
+				} else {
+					log.Panicf("Requirements genearation of %+v failed due to: %s\n", sig, er)
 				}
-				fmt.Println(sig)
-				// inter.GenerateStatements(declar, buf)
 			}
+			declar := function.FunctionDeclarationForInsertion{
+				FunctionDeclaration:  sig,
+				ParameterDescription: paras,
+				ParameterConstants:   constants,
+				Description:          utils.JoinBlocks(content["basic-description"]),
+				Requirements:         req.String(),
+				ParsedRequirements:   req,
+				ReturnValue:          function.HandleReturnValueSectionOfFunction(content["return-value"], sig.ReturnType),
+				RenderedDescription:  utils.RenderBlocks(content["basic-description"], path),
+				RenderedParameters:   rendered,
+			}
+			if er := inter.AddToFunctionSymbol(db, declar); er != nil {
+				log.Panicln("Some error in db: ", er)
+			}
+			fmt.Println(sig)
+			// inter.GenerateStatements(declar, buf)
 			// stdoutbuf.Flush()
 		}()
 	}
@@ -0,0 +1,88 @@
+package ntquery
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/cloakwiss/ntdocs/utils"
+)
+
+type ClassData struct {
+	Name, Description, Requirement string
+	BaseClasses                    []string
+	Members                        []ClassMember
+	// In the order of the page, constructors and destructor are included
+	Methods []ClassMethod
+}
+
+type ClassMember struct {
+	Name, Datatype, BitWidth, Dimensions string
+}
+
+type ClassMethod struct {
+	Class, Name, Overload, Description string
+	// One of method, constructor, destructor or operator
+	Kind string
+	// Absolute url of the page of the method, empty when the page does not link it
+	Url string
+}
+
+// Class with the given name, ok is false when it is not filled
+func (s *Search) GetClass(class_name string) (classData ClassData, ok bool) {
+	if !tableExists(s.dbconnection, "ClassSymbols") {
+		return
+	}
+	var bases string
+	row := s.dbconnection.QueryRow(fmt.Sprintf(`SELECT name, ifnull(base_classes, ''), %s, ifnull(requirements, '')
+		FROM ClassSymbols WHERE name = ?;`, documentColumn("description", s.format)), class_name)
+	er := row.Scan(&classData.Name, &bases, &classData.Description, &classData.Requirement)
+	if er == sql.ErrNoRows {
+		return
+	} else if er != nil {
+		log.Panicf("Query of ClassSymbols table failed due to: %v", er)
+	}
+	if bases != "" {
+		classData.BaseClasses = strings.Split(bases, ", ")
+	}
+
+	members, er := s.dbconnection.Query(`SELECT ifnull(name, ''), datatype, ifnull(bit_width, ''), ifnull(dimensions, '')
+		FROM ClassMembers WHERE class_name = ? ORDER BY srno;`, class_name)
+	if er != nil {
+		log.Panicf("Query of ClassMembers table failed due to: %v", er)
+	}
+	for members.Next() {
+		var member ClassMember
+		if er := members.Scan(&member.Name, &member.Datatype, &member.BitWidth, &member.Dimensions); er != nil {
+			log.Panicf("Some error %v while scanning %s's result \n", er, "ClassMembers")
+		}
+		classData.Members = append(classData.Members, member)
+	}
+	if er := members.Close(); er != nil {
+		log.Panicf("Cannot close the result of ClassMembers: %v", er)
+	}
+
+	methods, er := s.dbconnection.Query(`SELECT name, ifnull(overload, ''), kind, ifnull(description, ''), ifnull(target_path, '')
+		FROM ClassMethods WHERE class_name = ? ORDER BY srno;`, class_name)
+	if er != nil {
+		log.Panicf("Query of ClassMethods table failed due to: %v", er)
+	}
+	for methods.Next() {
+		var (
+			method = ClassMethod{Class: classData.Name}
+			path   string
+		)
+		if er := methods.Scan(&method.Name, &method.Overload, &method.Kind, &method.Description, &path); er != nil {
+			log.Panicf("Some error %v while scanning %s's result \n", er, "ClassMethods")
+		}
+		if path != "" {
+			method.Url = utils.DocumentationUrl(path)
+		}
+		classData.Methods = append(classData.Methods, method)
+	}
+	if er := methods.Close(); er != nil {
+		log.Panicf("Cannot close the result of ClassMethods: %v", er)
+	}
+	return classData, true
+}
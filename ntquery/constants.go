@@ -0,0 +1,59 @@
+package ntquery
+
+import (
+	"database/sql"
+	"log"
+)
+
+// One of the flags or constants accepted by a parameter or member, Value is only valid when Resolved is true
+type ValueConstant struct {
+	Name, ValueText, Description string
+	Value                        int64
+	Resolved                     bool
+}
+
+// Constants documented for each member of the structure, keyed by the name of member
+func (s *Search) StructureConstants(structure_name string) map[string][]ValueConstant {
+	return queryConstants(s.dbconnection, "structure", structure_name)
+}
+
+func queryConstants(dbConnection *sql.DB, owner_kind, owner_name string) map[string][]ValueConstant {
+	if !tableExists(dbConnection, "ValueConstants") {
+		return nil
+	}
+
+	rows, er := dbConnection.Query(`SELECT member_name, ifnull(name, ''), ifnull(value_text, ''), ifnull(description, ''), ifnull(value, 0), value IS NOT NULL
+		FROM ValueConstants WHERE owner_kind = ? AND owner_name = ? ORDER BY member_name, srno;`, owner_kind, owner_name)
+	if er != nil {
+		log.Panicf("Query of ValueConstants table failed due to: %v", er)
+	}
+	defer rows.Close()
+
+	constants := make(map[string][]ValueConstant)
+	for rows.Next() {
+		var (
+			member   string
+			constant ValueConstant
+		)
+		if er := rows.Scan(&member, &constant.Name, &constant.ValueText, &constant.Description, &constant.Value, &constant.Resolved); er != nil {
+			log.Panicf("Some error %v while scanning %s's result \n", er, "ValueConstants")
+		}
+		constants[member] = append(constants[member], constant)
+	}
+	return constants
+}
+
+func (parameters FunctionParameters) attachConstants(constants map[string][]ValueConstant) {
+	for i := range parameters {
+		parameters[i].Constants = constants[parameters[i].Name]
+	}
+}
+
+// Tables filled by the later commands are created on first insertion, so they can be missing
+func tableExists(dbConnection *sql.DB, table string) bool {
+	var count int
+	if er := dbConnection.QueryRow(`SELECT count(*) FROM sqlite_master WHERE type = 'table' AND name = ?;`, table).Scan(&count); er != nil {
+		log.Panicf("Query of sqlite_master failed due to: %v", er)
+	}
+	return count > 0
+}
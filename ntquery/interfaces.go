@@ -0,0 +1,102 @@
+package ntquery
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+)
+
+type InterfaceData struct {
+	Name, IID, BaseInterface, Description, Requirement string
+	// Only the methods declared by this interface, in the order of the page (alphabetical)
+	Methods []MethodData
+}
+
+type MethodData struct {
+	Interface, Name, Description string
+	// Empty till the page of the method is scraped and filled
+	Return, CallingConvention string
+	Arity                     uint
+	FunctionParameters
+}
+
+// Interface with the given name, ok is false when it is not filled
+func (s *Search) GetInterface(interface_name string) (interfaceData InterfaceData, ok bool) {
+	if !tableExists(s.dbconnection, "InterfaceSymbols") {
+		return
+	}
+	row := s.dbconnection.QueryRow(fmt.Sprintf(`SELECT name, ifnull(iid, ''), ifnull(base_interface, ''), %s, ifnull(requirements, '')
+		FROM InterfaceSymbols WHERE name = ?;`, documentColumn("description", s.format)), interface_name)
+	er := row.Scan(&interfaceData.Name, &interfaceData.IID, &interfaceData.BaseInterface, &interfaceData.Description, &interfaceData.Requirement)
+	if er == sql.ErrNoRows {
+		return
+	} else if er != nil {
+		log.Panicf("Query of InterfaceSymbols table failed due to: %v", er)
+	}
+
+	methods, er := s.dbconnection.Query(`SELECT name, ifnull(description, ''), ifnull(return, ''), ifnull(calling_convention, ''), ifnull(arity, 0)
+		FROM InterfaceMethods WHERE interface_name = ? ORDER BY srno;`, interface_name)
+	if er != nil {
+		log.Panicf("Query of InterfaceMethods table failed due to: %v", er)
+	}
+	for methods.Next() {
+		method := MethodData{Interface: interfaceData.Name}
+		if er := methods.Scan(&method.Name, &method.Description, &method.Return, &method.CallingConvention, &method.Arity); er != nil {
+			log.Panicf("Some error %v while scanning %s's result \n", er, "InterfaceMethods")
+		}
+		interfaceData.Methods = append(interfaceData.Methods, method)
+	}
+	if er := methods.Close(); er != nil {
+		log.Panicf("Cannot close the result of InterfaceMethods: %v", er)
+	}
+
+	parameters, er := s.dbconnection.Prepare(fmt.Sprintf(`SELECT ifnull(name, ''), datatype, ifnull(usage, ''), %s,
+		ifnull(direction, ''), optional, reserved, ifnull(size_parameter, ''), ifnull(size_unit, '')
+		FROM InterfaceMethodParameters WHERE interface_name = ? AND method_name = ? ORDER BY srno;`, documentColumn("documentation", s.format)))
+	if er != nil {
+		log.Panicf("Failed to prepare the InterfaceMethodParameters query, due to: %v", er)
+	}
+	defer parameters.Close()
+	for i := range interfaceData.Methods {
+		method := &interfaceData.Methods[i]
+		rows, er := parameters.Query(interface_name, method.Name)
+		if er != nil {
+			log.Panicf("Query of InterfaceMethodParameters table failed due to: %v", er)
+		}
+		for rows.Next() {
+			var parameter FunctionParameter
+			if er := rows.Scan(&parameter.Name, &parameter.Datatype, &parameter.Usage, &parameter.Documentation,
+				&parameter.Direction, &parameter.Optional, &parameter.Reserved, &parameter.SizeParameter, &parameter.SizeUnit); er != nil {
+				log.Panicf("Some error %v while scanning %s's result \n", er, "InterfaceMethodParameters")
+			}
+			method.FunctionParameters = append(method.FunctionParameters, parameter)
+		}
+		rows.Close()
+	}
+	return interfaceData, true
+}
+
+// Methods of the interface with the methods of its base interfaces first. Within an interface the order is of
+// the methods table in its page, which is alphabetical and not the order of declaration, so the index is not the
+// slot in the vtable. Missing is the first interface in the chain which is not filled, it is empty when the chain
+// reaches IUnknown.
+func (s *Search) Methods(interface_name string) (methods []MethodData, missing string) {
+	var (
+		chain []InterfaceData
+		seen  = make(map[string]bool)
+	)
+	for name := interface_name; name != "" && !seen[name]; {
+		seen[name] = true
+		data, ok := s.GetInterface(name)
+		if !ok {
+			missing = name
+			break
+		}
+		chain = append(chain, data)
+		name = data.BaseInterface
+	}
+	for i := len(chain) - 1; i >= 0; i -= 1 {
+		methods = append(methods, chain[i].Methods...)
+	}
+	return
+}
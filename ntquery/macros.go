@@ -0,0 +1,68 @@
+package ntquery
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+)
+
+type MacroData struct {
+	Name, Body, Return, Description, Requirement string
+	// Nil for object like macro
+	Parameters []string
+	// Documented parameters of the function form, datatype is empty when not written in the page
+	FunctionParameters
+}
+
+// Macro with the given name, ok is false when it is not a macro
+func (s *Search) GetMacro(macro_name string) (macroData MacroData, ok bool) {
+	if !tableExists(s.dbconnection, "MacroSymbols") {
+		return
+	}
+	var parameters sql.NullString
+	row := s.dbconnection.QueryRow(fmt.Sprintf(`SELECT name, parameters, ifnull(body, ''), ifnull(return, ''), %s, ifnull(requirements, '')
+		FROM MacroSymbols WHERE name = ?;`, documentColumn("description", s.format)), macro_name)
+	er := row.Scan(&macroData.Name, &parameters, &macroData.Body, &macroData.Return, &macroData.Description, &macroData.Requirement)
+	if er == sql.ErrNoRows {
+		return
+	} else if er != nil {
+		log.Panicf("Query of MacroSymbols table failed due to: %v", er)
+	}
+	if parameters.Valid {
+		macroData.Parameters = make([]string, 0)
+		for _, parameter := range strings.Split(parameters.String, ",") {
+			if parameter = strings.TrimSpace(parameter); parameter != "" {
+				macroData.Parameters = append(macroData.Parameters, parameter)
+			}
+		}
+	}
+
+	rows, er := s.dbconnection.Query(fmt.Sprintf(`SELECT ifnull(name, ''), ifnull(datatype, ''), ifnull(usage, ''), %s
+		FROM MacroParameters WHERE macro_name = ? ORDER BY srno;`, documentColumn("documentation", s.format)), macro_name)
+	if er != nil {
+		log.Panicf("Query of MacroParameters table failed due to: %v", er)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var parameter FunctionParameter
+		if er := rows.Scan(&parameter.Name, &parameter.Datatype, &parameter.Usage, &parameter.Documentation); er != nil {
+			log.Panicf("Some error %v while scanning %s's result \n", er, "MacroParameters")
+		}
+		macroData.FunctionParameters = append(macroData.FunctionParameters, parameter)
+	}
+	return macroData, true
+}
+
+// True only for the functions which can be imported from a DLL, macros share the function pages
+// but are not exported by anything
+func (s *Search) IsImportable(symbol_name string) bool {
+	if _, isMacro := s.GetMacro(symbol_name); isMacro {
+		return false
+	}
+	var count int
+	if er := s.dbconnection.QueryRow(`SELECT count(*) FROM FunctionSymbols WHERE name = ?;`, symbol_name).Scan(&count); er != nil {
+		log.Panicf("Query of FunctionSymbols table failed due to: %v", er)
+	}
+	return count > 0
+}
@@ -2,13 +2,64 @@ package ntquery
 
 import (
 	"database/sql"
+	"fmt"
 	"log"
 )
 
 type Search struct {
 	dbconnection *sql.DB
 	cache        map[string]FunctionData
+	callbacks    map[string]CallbackData
 	size         uint
+	format       Format
+}
+
+// Form in which description and documentation are returned, html is the default
+type Format uint8
+
+const (
+	FormatHTML Format = iota
+	FormatMarkdown
+	FormatText
+)
+
+// Selects the form of documentation as per the consumer, i.e. Markdown for hover tooltips and plain text for terminal.
+// Cached results are dropped when the format changes.
+func (s *Search) SetFormat(format Format) {
+	if s.format != format {
+		s.format = format
+		clear(s.cache)
+		clear(s.callbacks)
+	}
+}
+
+// Column holding the documentation in given format, falls back to html for the rows filled before the rendering
+func documentColumn(column string, format Format) string {
+	switch format {
+	case FormatMarkdown:
+		return fmt.Sprintf("ifnull(%s_markdown, %s)", column, column)
+	case FormatText:
+		return fmt.Sprintf("ifnull(%s_text, %s)", column, column)
+	default:
+		return column
+	}
+}
+
+// Column added to the table after it was first created, NULL when the database does not have it yet. Search can be
+// given any database, not only the ones opened by inter.OpenDB which adds them.
+func optionalColumn(dbConnection *sql.DB, table, column string) string {
+	if columnExists(dbConnection, table, column) {
+		return table + "." + column
+	}
+	return "NULL"
+}
+
+// Same as documentColumn, but the html is used when the rendered forms are not in the table
+func optionalDocumentColumn(dbConnection *sql.DB, table, column string, format Format) string {
+	if columnExists(dbConnection, table, column+"_markdown") {
+		return documentColumn(table+"."+column, format)
+	}
+	return table + "." + column
 }
 
 // TODO: This connection needs to be closed properly
@@ -17,7 +68,9 @@ func NewSearch(connection *sql.DB, cacheSize uint) Search {
 	return Search{
 		connection,
 		make(map[string]FunctionData),
+		make(map[string]CallbackData),
 		cacheSize,
+		FormatHTML,
 	}
 }
 
@@ -25,22 +78,72 @@ func (s *Search) Get(function_name string) FunctionData {
 	if data, found := s.cache[function_name]; found {
 		return data
 	}
-	data := query(s.dbconnection, function_name)
+	data := query(s.dbconnection, function_name, s.format)
+	data.FunctionParameters.attachConstants(queryConstants(s.dbconnection, "function", data.Name))
 	s.cache[function_name] = data
 	return data
 }
 
+// Callback can be searched either by its typedef or the name of the placeholder function shown in the page.
+// ok is false when it is not filled, or the placeholder is shared by several callbacks.
+func (s *Search) GetCallback(callback_name string) (CallbackData, bool) {
+	if data, found := s.callbacks[callback_name]; found {
+		return data, true
+	}
+	name, found := callbackName(s.dbconnection, callback_name)
+	if !found {
+		return CallbackData{}, false
+	}
+	data := queryCallback(s.dbconnection, name, s.format)
+	data.FunctionParameters.attachConstants(queryConstants(s.dbconnection, "callback", data.Name))
+	s.callbacks[callback_name] = data
+	return data, true
+}
+
+// Typedef of the callback, the typedef is preferred over the placeholder function as pages can share the placeholder
+func callbackName(dbConnection *sql.DB, callback_name string) (string, bool) {
+	if !tableExists(dbConnection, "CallbackSymbols") {
+		return "", false
+	}
+	rows, er := dbConnection.Query(`SELECT name FROM CallbackSymbols WHERE name = ?1
+		UNION ALL SELECT name FROM CallbackSymbols WHERE function_name = ?1 AND NOT EXISTS (SELECT 1 FROM CallbackSymbols WHERE name = ?1)
+		LIMIT 2;`, callback_name)
+	if er != nil {
+		log.Panicf("Query of Callback Symbols table failed due to: %v", er)
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if er := rows.Scan(&name); er != nil {
+			log.Panicf("Some error %v while scanning %s's result \n", er, "CallbackSymbol")
+		}
+		names = append(names, name)
+	}
+	if len(names) != 1 {
+		return "", false
+	}
+	return names[0], true
+}
+
 // This function interacts with the database for query and should not be called directly
-func query(dbConnection *sql.DB, function_name string) FunctionData {
-	functionSymbols, er := dbConnection.Prepare(`SELECT FunctionSymbols.name, FunctionSymbols.arity, FunctionSymbols.return, FunctionSymbols.description, FunctionSymbols.requirements
-		FROM FunctionSymbols WHERE FunctionSymbols.name = ?;`)
+func query(dbConnection *sql.DB, function_name string, format Format) FunctionData {
+	functionSymbols, er := dbConnection.Prepare(fmt.Sprintf(`SELECT FunctionSymbols.name, FunctionSymbols.arity, FunctionSymbols.return, %s, FunctionSymbols.requirements,
+		ifnull(%s, ''), ifnull(%s, ''), ifnull(%s, ''), ifnull(%s, ''), ifnull(%s, 0)
+		FROM FunctionSymbols WHERE FunctionSymbols.name = ?;`, optionalDocumentColumn(dbConnection, "FunctionSymbols", "description", format),
+		optionalColumn(dbConnection, "FunctionSymbols", "return_documentation"), optionalColumn(dbConnection, "FunctionSymbols", "error_convention"),
+		optionalColumn(dbConnection, "FunctionSymbols", "success_value"), optionalColumn(dbConnection, "FunctionSymbols", "failure_value"),
+		optionalColumn(dbConnection, "FunctionSymbols", "sets_last_error")))
 	if er != nil {
 		log.Panicf("Failed to prepare the FunctionSymbols query, due to: %v", er)
 	}
 	defer functionSymbols.Close()
 
-	functionParameters, er := dbConnection.Prepare(`SELECT FunctionParameters.srno, FunctionParameters.name, FunctionParameters.datatype, FunctionParameters.usage, FunctionParameters.documentation
-		FROM FunctionParameters WHERE FunctionParameters.function_name = ? AND FunctionParameters.srno <= ? ORDER BY FunctionParameters.srno;`)
+	functionParameters, er := dbConnection.Prepare(fmt.Sprintf(`SELECT FunctionParameters.srno, FunctionParameters.name, FunctionParameters.datatype, FunctionParameters.usage, %s,
+		%s
+		FROM FunctionParameters WHERE FunctionParameters.function_name = ? AND FunctionParameters.srno <= ? ORDER BY FunctionParameters.srno;`,
+		optionalDocumentColumn(dbConnection, "FunctionParameters", "documentation", format), usageSelection(dbConnection, "FunctionParameters")))
 	if er != nil {
 		log.Panic("Failed to prepare the FunctionParameter query, due to: %+w", er)
 	}
@@ -55,7 +158,8 @@ func query(dbConnection *sql.DB, function_name string) FunctionData {
 		defer resultingSymbol.Close()
 
 		if resultingSymbol.Next() {
-			if er := resultingSymbol.Scan(&functionData.Name, &functionData.Arity, &functionData.Return, &functionData.Description, &functionData.Requirement); er != nil {
+			if er := resultingSymbol.Scan(&functionData.Name, &functionData.Arity, &functionData.Return, &functionData.Description, &functionData.Requirement,
+				&functionData.ReturnDocumentation, &functionData.ErrorConvention, &functionData.SuccessValue, &functionData.FailureValue, &functionData.SetsLastError); er != nil {
 				log.Panicf("Some error %v while scanning %s's result \n", er, "FunctionSymbol")
 			}
 		}
@@ -77,7 +181,8 @@ func query(dbConnection *sql.DB, function_name string) FunctionData {
 		for i := 0; i < int(functionData.Arity) && resultingParameters.Next(); i += 1 {
 			var functionPara FunctionParameter
 			var num int
-			if er := resultingParameters.Scan(&num, &functionPara.Name, &functionPara.Datatype, &functionPara.Usage, &functionPara.Documentation); er != nil {
+			if er := resultingParameters.Scan(&num, &functionPara.Name, &functionPara.Datatype, &functionPara.Usage, &functionPara.Documentation,
+				&functionPara.Direction, &functionPara.Optional, &functionPara.Reserved, &functionPara.SizeParameter, &functionPara.SizeUnit); er != nil {
 				log.Panicf("Some error %v while scanning %s's result \n", er, "FunctionParameter")
 			}
 			// This should not be possible
@@ -94,14 +199,110 @@ func query(dbConnection *sql.DB, function_name string) FunctionData {
 	return functionData
 }
 
+// Direction, optional, reserved, size_parameter and size_unit of the parameters table in this order
+func usageSelection(dbConnection *sql.DB, table string) string {
+	return fmt.Sprintf("ifnull(%s, ''), ifnull(%s, 0), ifnull(%s, 0), ifnull(%s, ''), ifnull(%s, '')",
+		optionalColumn(dbConnection, table, "direction"), optionalColumn(dbConnection, table, "optional"),
+		optionalColumn(dbConnection, table, "reserved"), optionalColumn(dbConnection, table, "size_parameter"),
+		optionalColumn(dbConnection, table, "size_unit"))
+}
+
 type FunctionData struct {
 	Name, Return, Description, Requirement string
 	Arity                                  uint
 	FunctionParameters
+	ReturnValue
+}
+
+// ErrorConvention is one of "none", "nonzero", "null", "invalid-handle", "hresult", "ntstatus", "win32-error" or "unknown",
+// it is empty for callbacks
+type ReturnValue struct {
+	ReturnDocumentation, ErrorConvention string
+	SuccessValue, FailureValue           string
+	SetsLastError                        bool
 }
 
 type FunctionParameters []FunctionParameter
 
 type FunctionParameter struct {
 	Name, Datatype, Usage, Documentation string
+	// Normalized form of Usage, Direction is one of "in", "out", "inout" or empty
+	Direction          string
+	Optional, Reserved bool
+	// Parameter giving the size of this buffer and its unit "bytes" or "elements"
+	SizeParameter, SizeUnit string
+	// Flags or constants accepted by the parameter, nil when documentation has no such table
+	Constants []ValueConstant
+}
+
+// This function interacts with the database for query and should not be called directly, callback_name is the typedef
+func queryCallback(dbConnection *sql.DB, callback_name string, format Format) CallbackData {
+	callbackSymbols, er := dbConnection.Prepare(fmt.Sprintf(`SELECT CallbackSymbols.name, CallbackSymbols.function_name, ifnull(CallbackSymbols.calling_convention, ''),
+		CallbackSymbols.is_pointer, CallbackSymbols.arity, CallbackSymbols.return, %s, CallbackSymbols.requirements
+		FROM CallbackSymbols WHERE CallbackSymbols.name = ?;`, optionalDocumentColumn(dbConnection, "CallbackSymbols", "description", format)))
+	if er != nil {
+		log.Panicf("Failed to prepare the CallbackSymbols query, due to: %v", er)
+	}
+	defer callbackSymbols.Close()
+
+	callbackParameters, er := dbConnection.Prepare(fmt.Sprintf(`SELECT CallbackParameters.srno, CallbackParameters.name, CallbackParameters.datatype, CallbackParameters.usage, %s,
+		%s
+		FROM CallbackParameters WHERE CallbackParameters.callback_name = ? ORDER BY CallbackParameters.srno;`,
+		optionalDocumentColumn(dbConnection, "CallbackParameters", "documentation", format), usageSelection(dbConnection, "CallbackParameters")))
+	if er != nil {
+		log.Panicf("Failed to prepare the CallbackParameters query, due to: %v", er)
+	}
+	defer callbackParameters.Close()
+
+	var callbackData CallbackData
+	{
+		resultingSymbol, er := callbackSymbols.Query(callback_name)
+		if er != nil {
+			log.Panicf("Query of Callback Symbols table failed due to: %v", er)
+		}
+		defer resultingSymbol.Close()
+
+		if resultingSymbol.Next() {
+			if er := resultingSymbol.Scan(&callbackData.Name, &callbackData.FunctionName, &callbackData.CallingConvention, &callbackData.IsPointer,
+				&callbackData.Arity, &callbackData.Return, &callbackData.Description, &callbackData.Requirement); er != nil {
+				log.Panicf("Some error %v while scanning %s's result \n", er, "CallbackSymbol")
+			}
+		}
+		if callbackData.Name != callback_name {
+			log.Panicln("Search failed!!!!")
+		}
+	}
+	{
+		resultingParameters, er := callbackParameters.Query(callbackData.Name)
+		if er != nil {
+			log.Panicf("Query of Callback Parameter table failed due to: %v", er)
+		}
+		defer resultingParameters.Close()
+
+		callbackData.FunctionParameters = make(FunctionParameters, 0, int(callbackData.Arity))
+		for i := 0; resultingParameters.Next(); i += 1 {
+			var callbackPara FunctionParameter
+			var num int
+			if er := resultingParameters.Scan(&num, &callbackPara.Name, &callbackPara.Datatype, &callbackPara.Usage, &callbackPara.Documentation,
+				&callbackPara.Direction, &callbackPara.Optional, &callbackPara.Reserved, &callbackPara.SizeParameter, &callbackPara.SizeUnit); er != nil {
+				log.Panicf("Some error %v while scanning %s's result \n", er, "CallbackParameter")
+			}
+			// This should not be possible
+			if num != i+1 {
+				log.Panicln("Out of order")
+			}
+			callbackData.FunctionParameters = append(callbackData.FunctionParameters, callbackPara)
+		}
+		if len(callbackData.FunctionParameters) != int(callbackData.Arity) {
+			log.Panicf("Expected %d rows in result, found %d.\n", callbackData.Arity, len(callbackData.FunctionParameters))
+		}
+	}
+	return callbackData
+}
+
+// Callback is a function type, so it shares most of the data with function
+type CallbackData struct {
+	FunctionData
+	FunctionName, CallingConvention string
+	IsPointer                       bool
 }
@@ -0,0 +1,65 @@
+package ntquery
+
+import (
+	"database/sql"
+	"log"
+)
+
+// Link from one symbol's documentation to other, Section is the section of Source in which link was found
+type Reference struct {
+	Source, Target, Section, Text string
+}
+
+// Inner html of remarks section, empty when page has no remarks
+func (s *Search) Remarks(symbol_name string) string {
+	return s.section(symbol_name, "remarks")
+}
+
+// Inner html of see also section
+func (s *Search) SeeAlso(symbol_name string) string {
+	return s.section(symbol_name, "see-also")
+}
+
+func (s *Search) section(symbol_name, section string) (content string) {
+	if !tableExists(s.dbconnection, "SymbolSections") {
+		return
+	}
+	er := s.dbconnection.QueryRow(`SELECT ifnull(content, '') FROM SymbolSections WHERE symbol_name = ? AND section = ?;`,
+		symbol_name, section).Scan(&content)
+	if er != nil && er != sql.ErrNoRows {
+		log.Panicf("Query of SymbolSections table failed due to: %v", er)
+	}
+	return
+}
+
+// Symbols linked from the documentation of this symbol, the ones from see also section come first
+func (s *Search) RelatedAPIs(symbol_name string) []string {
+	if !tableExists(s.dbconnection, "SymbolReferences") {
+		return nil
+	}
+	return s.symbolNames(`SELECT target FROM SymbolReferences WHERE source = ?1 AND target IS NOT NULL AND target != ?1
+		GROUP BY target ORDER BY min(section != 'see-also'), min(srno), target;`, symbol_name)
+}
+
+// All the places where this symbol is linked from other symbols i.e. what references `VirtualAlloc`
+func (s *Search) ReferencedBy(symbol_name string) []Reference {
+	if !tableExists(s.dbconnection, "SymbolReferences") {
+		return nil
+	}
+	rows, er := s.dbconnection.Query(`SELECT source, target, section, ifnull(text, '') FROM SymbolReferences
+		WHERE target = ?1 AND source != ?1 ORDER BY source, section, srno;`, symbol_name)
+	if er != nil {
+		log.Panicf("Query of SymbolReferences table failed due to: %v", er)
+	}
+	defer rows.Close()
+
+	var references []Reference
+	for rows.Next() {
+		var reference Reference
+		if er := rows.Scan(&reference.Source, &reference.Target, &reference.Section, &reference.Text); er != nil {
+			log.Panicf("Some error %v while scanning %s's result \n", er, "SymbolReferences")
+		}
+		references = append(references, reference)
+	}
+	return references
+}
@@ -0,0 +1,102 @@
+package ntquery
+
+import (
+	"database/sql"
+	"log"
+)
+
+type Requirements struct {
+	SymbolName, Header, TargetPlatform string
+	MinimumClient, MinimumServer       Version
+	IncludeChain, Libraries, DLLs      []string
+	APISets                            []string
+}
+
+// Major, Minor and Build are of NT kernel, they are zero when not known
+type Version struct {
+	Text                string
+	Major, Minor, Build int
+}
+
+// Requirements of any symbol which has been filled, ok is false when nothing is recorded for it
+func (s *Search) GetRequirements(symbol_name string) (requirements Requirements, ok bool) {
+	if !tableExists(s.dbconnection, "SymbolRequirements") {
+		return
+	}
+	row := s.dbconnection.QueryRow(`SELECT symbol_name, ifnull(header, ''), ifnull(target_platform, ''),
+		ifnull(min_client, ''), ifnull(min_client_major, 0), ifnull(min_client_minor, 0), ifnull(min_client_build, 0),
+		ifnull(min_server, ''), ifnull(min_server_major, 0), ifnull(min_server_minor, 0), ifnull(min_server_build, 0)
+		FROM SymbolRequirements WHERE symbol_name = ?;`, symbol_name)
+	er := row.Scan(&requirements.SymbolName, &requirements.Header, &requirements.TargetPlatform,
+		&requirements.MinimumClient.Text, &requirements.MinimumClient.Major, &requirements.MinimumClient.Minor, &requirements.MinimumClient.Build,
+		&requirements.MinimumServer.Text, &requirements.MinimumServer.Major, &requirements.MinimumServer.Minor, &requirements.MinimumServer.Build)
+	if er == sql.ErrNoRows {
+		return
+	} else if er != nil {
+		log.Panicf("Query of SymbolRequirements table failed due to: %v", er)
+	}
+
+	if !tableExists(s.dbconnection, "SymbolRequirementItems") {
+		return requirements, true
+	}
+	items, er := s.dbconnection.Query(`SELECT kind, value FROM SymbolRequirementItems WHERE symbol_name = ? ORDER BY kind, srno;`, symbol_name)
+	if er != nil {
+		log.Panicf("Query of SymbolRequirementItems table failed due to: %v", er)
+	}
+	defer items.Close()
+	for items.Next() {
+		var kind, value string
+		if er := items.Scan(&kind, &value); er != nil {
+			log.Panicf("Some error %v while scanning %s's result \n", er, "SymbolRequirementItems")
+		}
+		switch kind {
+		case "include":
+			requirements.IncludeChain = append(requirements.IncludeChain, value)
+		case "library":
+			requirements.Libraries = append(requirements.Libraries, value)
+		case "dll":
+			requirements.DLLs = append(requirements.DLLs, value)
+		case "api_set":
+			requirements.APISets = append(requirements.APISets, value)
+		}
+	}
+	return requirements, true
+}
+
+// All the symbols exported by the DLL, name is matched case insensitively i.e. "kernel32.dll"
+func (s *Search) SymbolsInDLL(dll string) []string {
+	if !tableExists(s.dbconnection, "SymbolRequirementItems") {
+		return nil
+	}
+	return s.symbolNames(`SELECT symbol_name FROM SymbolRequirementItems
+		WHERE kind = 'dll' AND value = ? COLLATE NOCASE ORDER BY symbol_name;`, dll)
+}
+
+// All the symbols whose minimum supported client is same or newer than the given NT version,
+// Windows 10, version 1709 is 10.0.16299
+func (s *Search) SymbolsSinceClient(major, minor, build int) []string {
+	if !tableExists(s.dbconnection, "SymbolRequirements") {
+		return nil
+	}
+	return s.symbolNames(`SELECT symbol_name FROM SymbolRequirements
+		WHERE min_client_major IS NOT NULL AND (min_client_major, min_client_minor, min_client_build) >= (?, ?, ?)
+		ORDER BY symbol_name;`, major, minor, build)
+}
+
+func (s *Search) symbolNames(query string, args ...any) []string {
+	rows, er := s.dbconnection.Query(query, args...)
+	if er != nil {
+		log.Panicf("Query failed due to: %v", er)
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if er := rows.Scan(&name); er != nil {
+			log.Panicf("Some error %v while scanning the result \n", er)
+		}
+		names = append(names, name)
+	}
+	return names
+}
@@ -0,0 +1,212 @@
+package ntquery
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+)
+
+type StructData struct {
+	// Name of the typedef as in StructureSymbols, Tag is the name after `struct` and is empty for anonymous ones
+	Name, Tag string
+	// "struct" or "union", empty when the layout is not filled
+	Kind                     string
+	Description, Requirement string
+	// Pointer typedefs declared with the structure i.e. `PACTRL_ACCESS_ENTRY_LISTA`
+	Aliases []string
+	Members []StructMember
+}
+
+type StructMember struct {
+	// Datatype contains the array dimensions i.e. `WCHAR[MAX_PATH]`
+	Name, Datatype, Documentation string
+	// Flags or constants accepted by the member, nil when documentation has no such table
+	Constants []ValueConstant
+}
+
+type EnumData struct {
+	Name, Tag, Description string
+	Constants              []EnumConstant
+}
+
+type EnumConstant struct {
+	Name, Expression, Documentation string
+	// Only valid when Resolved is true
+	Value    int64
+	Resolved bool
+}
+
+// Structure or union searched by its typedef, its tag or one of its pointer typedefs. ok is false when it is not filled
+func (s *Search) GetStruct(struct_name string) (structData StructData, ok bool) {
+	if !tableExists(s.dbconnection, "StructureSymbols") {
+		return
+	}
+	name, found := s.structureName(struct_name)
+	if !found {
+		return
+	}
+
+	descriptionColumn := "description"
+	if columnExists(s.dbconnection, "StructureSymbols", "description_markdown") {
+		descriptionColumn = documentColumn(descriptionColumn, s.format)
+	}
+	row := s.dbconnection.QueryRow(fmt.Sprintf(`SELECT name, ifnull(%s, ''), ifnull(requirement, '')
+		FROM StructureSymbols WHERE name = ?;`, descriptionColumn), name)
+	er := row.Scan(&structData.Name, &structData.Description, &structData.Requirement)
+	if er == sql.ErrNoRows {
+		return
+	} else if er != nil {
+		log.Panicf("Query of StructureSymbols table failed due to: %v", er)
+	}
+
+	if tableExists(s.dbconnection, "StructureFields") {
+		er := s.dbconnection.QueryRow(`SELECT kind, ifnull(tag, '') FROM StructureFields WHERE structure_name = ? AND id = 0;`, name).
+			Scan(&structData.Kind, &structData.Tag)
+		if er != nil && er != sql.ErrNoRows {
+			log.Panicf("Query of StructureFields table failed due to: %v", er)
+		}
+	}
+
+	if tableExists(s.dbconnection, "StructurePointer") {
+		aliases, er := s.dbconnection.Query(`SELECT ltrim(pointer_name, '* ') FROM StructurePointer WHERE structure_name = ?;`, name)
+		if er != nil {
+			log.Panicf("Query of StructurePointer table failed due to: %v", er)
+		}
+		for aliases.Next() {
+			var alias string
+			if er := aliases.Scan(&alias); er != nil {
+				log.Panicf("Some error %v while scanning %s's result \n", er, "StructurePointer")
+			}
+			structData.Aliases = append(structData.Aliases, alias)
+		}
+		if er := aliases.Close(); er != nil {
+			log.Panicf("Cannot close the result of StructurePointer: %v", er)
+		}
+	}
+
+	documentation := "''"
+	if columnExists(s.dbconnection, "StructureMembers", "documentation") {
+		documentation = fmt.Sprintf("ifnull(%s, '')", documentColumn("documentation", s.format))
+	}
+	members, er := s.dbconnection.Query(fmt.Sprintf(`SELECT ifnull(name, ''), datatype, %s
+		FROM StructureMembers WHERE structure_name = ? ORDER BY srno;`, documentation), name)
+	if er != nil {
+		log.Panicf("Query of StructureMembers table failed due to: %v", er)
+	}
+	for members.Next() {
+		var member StructMember
+		if er := members.Scan(&member.Name, &member.Datatype, &member.Documentation); er != nil {
+			log.Panicf("Some error %v while scanning %s's result \n", er, "StructureMembers")
+		}
+		structData.Members = append(structData.Members, member)
+	}
+	if er := members.Close(); er != nil {
+		log.Panicf("Cannot close the result of StructureMembers: %v", er)
+	}
+
+	constants := queryConstants(s.dbconnection, "structure", name)
+	for i := range structData.Members {
+		structData.Members[i].Constants = constants[structData.Members[i].Name]
+	}
+	return structData, true
+}
+
+// Name of the structure in StructureSymbols for the typedef, tag or pointer typedef
+func (s *Search) structureName(struct_name string) (string, bool) {
+	queries := []struct{ table, query string }{
+		{"StructureSymbols", `SELECT name FROM StructureSymbols WHERE name = ?;`},
+		{"StructureFields", `SELECT structure_name FROM StructureFields WHERE id = 0 AND tag = ?;`},
+		{"StructurePointer", `SELECT structure_name FROM StructurePointer WHERE ltrim(pointer_name, '* ') = ?;`},
+	}
+	for _, q := range queries {
+		if !tableExists(s.dbconnection, q.table) {
+			continue
+		}
+		var name string
+		er := s.dbconnection.QueryRow(q.query, struct_name).Scan(&name)
+		if er == nil {
+			return name, true
+		} else if er != sql.ErrNoRows {
+			log.Panicf("Query of %s table failed due to: %v", q.table, er)
+		}
+	}
+	return "", false
+}
+
+// Enumeration searched by its typedef or its tag, ok is false when it is not filled
+func (s *Search) GetEnum(enum_name string) (enumData EnumData, ok bool) {
+	if !tableExists(s.dbconnection, "EnumSymbols") {
+		return
+	}
+	row := s.dbconnection.QueryRow(`SELECT name, ifnull(tag, ''), ifnull(description, '')
+		FROM EnumSymbols WHERE name = ?1 OR tag = ?1 ORDER BY name = ?1 DESC LIMIT 1;`, enum_name)
+	er := row.Scan(&enumData.Name, &enumData.Tag, &enumData.Description)
+	if er == sql.ErrNoRows {
+		return
+	} else if er != nil {
+		log.Panicf("Query of EnumSymbols table failed due to: %v", er)
+	}
+
+	rows, er := s.dbconnection.Query(`SELECT name, ifnull(expression, ''), ifnull(documentation, ''), ifnull(value, 0), value IS NOT NULL
+		FROM EnumConstants WHERE enum_name = ? ORDER BY srno;`, enumData.Name)
+	if er != nil {
+		log.Panicf("Query of EnumConstants table failed due to: %v", er)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var constant EnumConstant
+		if er := rows.Scan(&constant.Name, &constant.Expression, &constant.Documentation, &constant.Value, &constant.Resolved); er != nil {
+			log.Panicf("Some error %v while scanning %s's result \n", er, "EnumConstants")
+		}
+		enumData.Constants = append(enumData.Constants, constant)
+	}
+	return enumData, true
+}
+
+// Kind of the symbol as per the table it is filled in, one of "function", "callback", "structure", "enumeration",
+// "macro", "interface" or "class". ok is false when it is not filled in any of them.
+// Macros are checked before functions, as they may still be present in FunctionSymbols.
+func (s *Search) Kind(symbol_name string) (kind string, ok bool) {
+	if _, found := s.GetMacro(symbol_name); found {
+		return "macro", true
+	}
+	queries := []struct{ kind, table, query string }{
+		{"function", "FunctionSymbols", `SELECT count(*) FROM FunctionSymbols WHERE name = ?1;`},
+		// Found by the same lookup as GetCallback
+		{"callback", "CallbackSymbols", ""},
+		{"enumeration", "EnumSymbols", `SELECT count(*) FROM EnumSymbols WHERE name = ?1 OR tag = ?1;`},
+		{"interface", "InterfaceSymbols", `SELECT count(*) FROM InterfaceSymbols WHERE name = ?1;`},
+		{"class", "ClassSymbols", `SELECT count(*) FROM ClassSymbols WHERE name = ?1;`},
+	}
+	for _, q := range queries {
+		if !tableExists(s.dbconnection, q.table) {
+			continue
+		}
+		if q.kind == "callback" {
+			if _, found := callbackName(s.dbconnection, symbol_name); found {
+				return q.kind, true
+			}
+			continue
+		}
+		var count int
+		if er := s.dbconnection.QueryRow(q.query, symbol_name).Scan(&count); er != nil {
+			log.Panicf("Query of %s table failed due to: %v", q.table, er)
+		}
+		if count > 0 {
+			return q.kind, true
+		}
+	}
+	if _, found := s.structureName(symbol_name); found {
+		return "structure", true
+	}
+	return "", false
+}
+
+// Columns added by the later versions are missing in the tables filled before them
+func columnExists(dbConnection *sql.DB, table, column string) bool {
+	var count int
+	if er := dbConnection.QueryRow(`SELECT count(*) FROM pragma_table_info(?) WHERE name = ?;`, table, column).Scan(&count); er != nil {
+		log.Panicf("Query of pragma_table_info failed due to: %v", er)
+	}
+	return count > 0
+}
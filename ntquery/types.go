@@ -0,0 +1,392 @@
+package ntquery
+
+import (
+	"database/sql"
+	"log"
+	"regexp"
+	"strings"
+)
+
+type TypeKind string
+
+const (
+	TypePrimitive TypeKind = "primitive"
+	// Typedef or define from win_type
+	TypeAlias     TypeKind = "alias"
+	TypePointer   TypeKind = "pointer"
+	TypeArray     TypeKind = "array"
+	TypeStruct    TypeKind = "struct"
+	TypeUnion     TypeKind = "union"
+	TypeEnum      TypeKind = "enum"
+	TypeFunction  TypeKind = "function"
+	TypeInterface TypeKind = "interface"
+	TypeClass     TypeKind = "class"
+	// Name is already being resolved higher in the tree, like `Flink` of `LIST_ENTRY` pointing to itself
+	TypeCycle      TypeKind = "cycle"
+	TypeUnresolved TypeKind = "unresolved"
+)
+
+// Node of the tree built by ResolveType
+type TypeNode struct {
+	// As written, for the pointer and array nodes it is the whole expression i.e. `CONST WCHAR *`
+	Name       string
+	Kind       TypeKind
+	Qualifiers []string
+	// Aliased type of alias, pointed type of pointer and element type of array
+	Target *TypeNode
+	// Size of the array as written i.e. `MAX_PATH`, empty when not given
+	Dimension string
+	// Members of struct and union, nested anonymous aggregates have empty Name
+	Members []TypeMember
+	// Signature of the function type, it is the target of a pointer for the callbacks declared as pointer
+	Return     *TypeNode
+	Parameters []TypeMember
+	// Why the node is unresolved
+	Reason string
+}
+
+type TypeMember struct {
+	Name, BitWidth string
+	Type           *TypeNode
+}
+
+var (
+	// Words of the builtin types of C and of the compiler, the combinations like `unsigned long` are also primitive
+	primitiveWords = map[string]bool{
+		"void": true, "char": true, "short": true, "int": true, "long": true, "float": true, "double": true,
+		"signed": true, "unsigned": true, "_Bool": true, "bool": true, "wchar_t": true, "size_t": true,
+		"__int8": true, "__int16": true, "__int32": true, "__int64": true, "__int3264": true,
+		"int8_t": true, "int16_t": true, "int32_t": true, "int64_t": true,
+		"uint8_t": true, "uint16_t": true, "uint32_t": true, "uint64_t": true,
+	}
+	qualifierWords = map[string]bool{
+		"const": true, "CONST": true, "volatile": true, "restrict": true, "__restrict": true,
+		"far": true, "near": true, "FAR": true, "NEAR": true, "__far": true, "__near": true,
+		"UNALIGNED": true, "__unaligned": true, "__ptr32": true, "__ptr64": true, "POINTER_32": true, "POINTER_64": true,
+	}
+	dimensionPattern = regexp.MustCompile(`\[([^\]]*)\]`)
+)
+
+// Resolves the type written as in the parameters and members i.e. `LPCWSTR`, `const char *` or `WCHAR[MAX_PATH]`
+// through the aliases in win_type, pointer typedefs of structures and their layout down to the primitive types.
+// Nodes which cannot be resolved are marked as TypeUnresolved instead of failing the whole tree.
+func (s *Search) ResolveType(type_name string) *TypeNode {
+	r := resolver{
+		search:   s,
+		visiting: make(map[string]bool),
+		resolved: make(map[string]*TypeNode),
+	}
+	return r.expression(type_name)
+}
+
+// Names of the unresolved nodes in the tree, each only once
+func (t *TypeNode) Unresolved() (names []string) {
+	var (
+		seen = make(map[*TypeNode]bool)
+		walk func(*TypeNode)
+	)
+	found := make(map[string]bool)
+	walk = func(node *TypeNode) {
+		if node == nil || seen[node] {
+			return
+		}
+		seen[node] = true
+		if node.Kind == TypeUnresolved && !found[node.Name] {
+			found[node.Name] = true
+			names = append(names, node.Name)
+		}
+		walk(node.Target)
+		walk(node.Return)
+		for _, member := range append(node.Members, node.Parameters...) {
+			walk(member.Type)
+		}
+	}
+	walk(t)
+	return
+}
+
+type resolver struct {
+	search *Search
+	// Names on the path from root to the current node
+	visiting map[string]bool
+	// Completed nodes of the names, shared in the tree
+	resolved map[string]*TypeNode
+}
+
+func (r *resolver) db() *sql.DB {
+	return r.search.dbconnection
+}
+
+func (r *resolver) expression(text string) *TypeNode {
+	text = strings.Join(strings.Fields(text), " ")
+
+	if index := strings.Index(text, "["); index >= 0 && strings.HasSuffix(text, "]") {
+		return arrayOf(r.expression(text[:index]), text[index:])
+	}
+	if index := strings.LastIndex(text, "*"); index >= 0 {
+		var qualifiers []string
+		for _, word := range strings.Fields(text[index+1:]) {
+			if qualifierWords[word] {
+				qualifiers = append(qualifiers, word)
+			}
+		}
+		return &TypeNode{Name: text, Kind: TypePointer, Qualifiers: qualifiers, Target: r.expression(text[:index])}
+	}
+
+	var (
+		qualifiers, words []string
+	)
+	for _, word := range strings.Fields(text) {
+		if qualifierWords[word] {
+			qualifiers = append(qualifiers, word)
+		} else {
+			words = append(words, word)
+		}
+	}
+	if len(words) == 0 {
+		return &TypeNode{Name: text, Kind: TypeUnresolved, Qualifiers: qualifiers, Reason: "no type in the expression"}
+	}
+
+	var node *TypeNode
+	switch {
+	case isPrimitive(words):
+		node = &TypeNode{Name: strings.Join(words, " "), Kind: TypePrimitive}
+	case len(words) == 2 && (words[0] == "struct" || words[0] == "union" || words[0] == "enum"):
+		node = r.tagged(words[0], words[1])
+	case len(words) == 1:
+		node = r.name(words[0])
+	default:
+		node = &TypeNode{Name: strings.Join(words, " "), Kind: TypeUnresolved, Reason: "unknown type expression"}
+	}
+	if len(qualifiers) > 0 {
+		// Shared node is not modified
+		qualified := *node
+		qualified.Qualifiers = qualifiers
+		node = &qualified
+	}
+	return node
+}
+
+func isPrimitive(words []string) bool {
+	for _, word := range words {
+		if !primitiveWords[word] {
+			return false
+		}
+	}
+	return true
+}
+
+// `struct _FOO` or `enum _BAR`, looked up by the tag
+func (r *resolver) tagged(keyword, tag string) *TypeNode {
+	if keyword == "enum" {
+		if data, found := r.search.GetEnum(tag); found {
+			return &TypeNode{Name: data.Name, Kind: TypeEnum}
+		}
+	} else if name, found := r.search.structureName(tag); found {
+		return r.structure(name)
+	}
+	return &TypeNode{Name: keyword + " " + tag, Kind: TypeUnresolved, Reason: keyword + " is not filled"}
+}
+
+func (r *resolver) name(name string) *TypeNode {
+	if node, found := r.resolved[name]; found {
+		return node
+	}
+	if r.visiting[name] {
+		return &TypeNode{Name: name, Kind: TypeCycle}
+	}
+	r.visiting[name] = true
+	node := r.lookup(name)
+	delete(r.visiting, name)
+	r.resolved[name] = node
+	return node
+}
+
+// win_type is checked first as the common types like `DWORD` are only there, rest of the tables have the
+// types of the pages
+func (r *resolver) lookup(name string) *TypeNode {
+	if tableExists(r.db(), "win_type") {
+		var (
+			aliasTo   string
+			isPointer bool
+		)
+		er := r.db().QueryRow(`SELECT ifnull(alias_to, ''), is_pointer FROM win_type WHERE name = ?;`, name).Scan(&aliasTo, &isPointer)
+		switch {
+		case er == sql.ErrNoRows:
+		case er != nil:
+			log.Panicf("Query of win_type table failed due to: %v", er)
+		// Types like `HANDLE` are shown without the definition
+		case aliasTo == "" || aliasTo == "null":
+			return &TypeNode{Name: name, Kind: TypeUnresolved, Reason: "win_type has no definition"}
+		default:
+			if isPointer {
+				aliasTo += " *"
+			}
+			return &TypeNode{Name: name, Kind: TypeAlias, Target: r.expression(aliasTo)}
+		}
+	}
+
+	if structure, found := r.structureByName(name); found {
+		return r.structure(structure)
+	}
+	if tableExists(r.db(), "StructurePointer") {
+		var structure string
+		er := r.db().QueryRow(`SELECT structure_name FROM StructurePointer WHERE ltrim(pointer_name, '* ') = ?;`, name).Scan(&structure)
+		if er == nil {
+			return &TypeNode{Name: name, Kind: TypePointer, Target: r.name(structure)}
+		} else if er != sql.ErrNoRows {
+			log.Panicf("Query of StructurePointer table failed due to: %v", er)
+		}
+	}
+	if data, found := r.search.GetEnum(name); found {
+		return &TypeNode{Name: data.Name, Kind: TypeEnum}
+	}
+	if kind, found := r.search.Kind(name); found {
+		switch kind {
+		case "callback":
+			return r.callback(name)
+		case "interface":
+			return &TypeNode{Name: name, Kind: TypeInterface}
+		case "class":
+			return &TypeNode{Name: name, Kind: TypeClass}
+		}
+	}
+	return &TypeNode{Name: name, Kind: TypeUnresolved, Reason: "not found in any table"}
+}
+
+// Name in StructureSymbols for the typedef or the tag, pointer typedefs are resolved separately as they add a pointer
+func (r *resolver) structureByName(name string) (string, bool) {
+	if !tableExists(r.db(), "StructureSymbols") {
+		return "", false
+	}
+	var structure string
+	er := r.db().QueryRow(`SELECT name FROM StructureSymbols WHERE name = ?;`, name).Scan(&structure)
+	if er == nil {
+		return structure, true
+	} else if er != sql.ErrNoRows {
+		log.Panicf("Query of StructureSymbols table failed due to: %v", er)
+	}
+	if tableExists(r.db(), "StructureFields") {
+		er := r.db().QueryRow(`SELECT structure_name FROM StructureFields WHERE id = 0 AND tag = ?;`, name).Scan(&structure)
+		if er == nil {
+			return structure, true
+		} else if er != sql.ErrNoRows {
+			log.Panicf("Query of StructureFields table failed due to: %v", er)
+		}
+	}
+	return "", false
+}
+
+// Layout is taken from StructureFields, StructureMembers is used for the structures filled before it
+func (r *resolver) structure(name string) *TypeNode {
+	// Typedef of the structure is usually its name too, which is already being visited by the lookup
+	key := "struct " + name
+	if node, found := r.resolved[key]; found {
+		return node
+	}
+	if r.visiting[key] {
+		return &TypeNode{Name: name, Kind: TypeCycle}
+	}
+	r.visiting[key] = true
+	defer delete(r.visiting, key)
+
+	node := &TypeNode{Name: name, Kind: TypeStruct}
+	if fields := r.fields(name); len(fields) > 0 {
+		root := fields[0]
+		if root.kind == "union" {
+			node.Kind = TypeUnion
+		}
+		node.Members = r.aggregate(fields, root.id)
+	} else if data, found := r.search.GetStruct(name); found {
+		for _, member := range data.Members {
+			node.Members = append(node.Members, TypeMember{Name: member.Name, Type: r.expression(member.Datatype)})
+		}
+	} else {
+		node = &TypeNode{Name: name, Kind: TypeUnresolved, Reason: "structure is not filled"}
+	}
+	r.resolved[key] = node
+	return node
+}
+
+// Row of StructureFields
+type layoutField struct {
+	id, parent                int
+	kind, datatype, name      string
+	bitWidth, dimensions, tag string
+}
+
+func (r *resolver) fields(name string) (fields []layoutField) {
+	if !tableExists(r.db(), "StructureFields") {
+		return
+	}
+	rows, er := r.db().Query(`SELECT id, ifnull(parent_id, -1), kind, ifnull(datatype, ''), ifnull(name, ''), ifnull(bit_width, ''),
+		ifnull(dimensions, ''), ifnull(tag, '') FROM StructureFields WHERE structure_name = ? ORDER BY id;`, name)
+	if er != nil {
+		log.Panicf("Query of StructureFields table failed due to: %v", er)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var field layoutField
+		if er := rows.Scan(&field.id, &field.parent, &field.kind, &field.datatype, &field.name, &field.bitWidth,
+			&field.dimensions, &field.tag); er != nil {
+			log.Panicf("Some error %v while scanning %s's result \n", er, "StructureFields")
+		}
+		fields = append(fields, field)
+	}
+	return
+}
+
+// Members of the aggregate with given id, fields are in preorder so the children come after their parent
+func (r *resolver) aggregate(fields []layoutField, parent int) (members []TypeMember) {
+	for _, field := range fields {
+		if field.parent != parent || field.id == parent {
+			continue
+		}
+		member := TypeMember{Name: field.name, BitWidth: field.bitWidth}
+		switch field.kind {
+		case "struct", "union":
+			kind := TypeStruct
+			if field.kind == "union" {
+				kind = TypeUnion
+			}
+			inner := &TypeNode{Name: strings.TrimSpace(field.kind + " " + field.tag), Kind: kind, Members: r.aggregate(fields, field.id)}
+			member.Type = arrayOf(inner, field.dimensions)
+		default:
+			member.Type = r.expression(field.datatype + field.dimensions)
+		}
+		members = append(members, member)
+	}
+	return
+}
+
+// Wraps the element in the dimensions written after it like `[MAX_PATH][2]`, outermost first
+func arrayOf(element *TypeNode, suffix string) *TypeNode {
+	var (
+		node  = element
+		sizes = dimensionPattern.FindAllStringSubmatch(suffix, -1)
+	)
+	for i := len(sizes) - 1; i >= 0; i -= 1 {
+		var written strings.Builder
+		for _, size := range sizes[i:] {
+			written.WriteString(size[0])
+		}
+		node = &TypeNode{Name: element.Name + written.String(), Kind: TypeArray, Dimension: strings.TrimSpace(sizes[i][1]), Target: node}
+	}
+	return node
+}
+
+// Callbacks are function types, the ones declared as pointer are pointer to them
+func (r *resolver) callback(name string) *TypeNode {
+	data, found := r.search.GetCallback(name)
+	if !found {
+		return &TypeNode{Name: name, Kind: TypeUnresolved, Reason: "callback is not found"}
+	}
+	function := &TypeNode{Name: data.Name, Kind: TypeFunction, Return: r.expression(data.Return)}
+	for _, parameter := range data.FunctionParameters {
+		function.Parameters = append(function.Parameters, TypeMember{Name: parameter.Name, Type: r.expression(parameter.Datatype)})
+	}
+	if data.IsPointer {
+		return &TypeNode{Name: name, Kind: TypePointer, Target: function}
+	}
+	return function
+}
@@ -0,0 +1,133 @@
+package ntquery_test
+
+import (
+	"database/sql"
+	"slices"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/cloakwiss/ntdocs/ntquery"
+)
+
+// Tables read by the resolver with a few rows, in a database which lives as long as the test
+func typesDB(t *testing.T) *sql.DB {
+	db, er := sql.Open("sqlite3", "file:"+t.Name()+"?mode=memory&cache=shared")
+	if er != nil {
+		t.Fatal(er)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	_, er = db.Exec(`
+	CREATE TABLE win_type (
+		name        TEXT PRIMARY KEY,
+		alias_type  TEXT CHECK(alias_type IN ('typedef', 'define')) NULL,
+		alias_to    TEXT NULL,
+		description TEXT,
+		is_pointer  BOOLEAN NOT NULL DEFAULT 0
+	);
+	INSERT INTO win_type (name, alias_type, alias_to, description, is_pointer) VALUES
+		('LPCWSTR', 'typedef', 'CONST WCHAR', '', 1),
+		('WCHAR', 'typedef', 'wchar_t', '', 0),
+		('DWORD', 'typedef', 'unsigned long', '', 0),
+		('HANDLE', 'typedef', NULL, '', 0);
+
+	CREATE TABLE StructureSymbols (
+		name         TEXT NOT NULL,
+		member_count INTEGER,
+		description  TEXT,
+		requirement  TEXT
+	);
+	CREATE TABLE StructurePointer (
+		pointer_name   TEXT NOT NULL,
+		structure_name TEXT NOT NULL
+	);
+	CREATE TABLE StructureFields (
+		structure_name TEXT NOT NULL,
+		id             INTEGER NOT NULL,
+		parent_id      INTEGER NULL,
+		srno           INTEGER NOT NULL,
+		kind           TEXT CHECK(kind IN ('struct', 'union', 'field')) NOT NULL,
+		tag            TEXT NULL,
+		datatype       TEXT NULL,
+		name           TEXT NULL,
+		bit_width      TEXT NULL,
+		dimensions     TEXT NULL,
+		PRIMARY KEY (structure_name, id)
+	);
+	INSERT INTO StructureSymbols (name, member_count) VALUES ('LIST_ENTRY', 2), ('FOO', 3);
+	INSERT INTO StructurePointer (pointer_name, structure_name) VALUES ('*PLIST_ENTRY', 'LIST_ENTRY'), ('*PFOO', 'FOO');
+	INSERT INTO StructureFields (structure_name, id, parent_id, srno, kind, tag, datatype, name, dimensions) VALUES
+		('LIST_ENTRY', 0, NULL, 0, 'struct', '_LIST_ENTRY', NULL, 'LIST_ENTRY', NULL),
+		('LIST_ENTRY', 1, 0, 1, 'field', NULL, 'struct _LIST_ENTRY *', 'Flink', NULL),
+		('LIST_ENTRY', 2, 0, 2, 'field', NULL, 'struct _LIST_ENTRY *', 'Blink', NULL),
+		('FOO', 0, NULL, 0, 'struct', '_FOO', NULL, 'FOO', NULL),
+		('FOO', 1, 0, 1, 'field', NULL, 'WCHAR', 'Name', '[MAX_PATH][2]'),
+		('FOO', 2, 0, 2, 'field', NULL, 'HFOO', 'First', NULL),
+		('FOO', 3, 0, 3, 'field', NULL, 'HFOO', 'Second', NULL);`)
+	if er != nil {
+		t.Fatal(er)
+	}
+	return db
+}
+
+func TestResolveType(t *testing.T) {
+	search := ntquery.NewSearch(typesDB(t), 0)
+
+	node := search.ResolveType("LPCWSTR")
+	if node.Kind != ntquery.TypeAlias || node.Target == nil || node.Target.Kind != ntquery.TypePointer {
+		t.Fatalf("LPCWSTR is not an alias of pointer: %+v", node)
+	}
+	wchar := node.Target.Target
+	if wchar.Kind != ntquery.TypeAlias || wchar.Name != "WCHAR" || !slices.Equal(wchar.Qualifiers, []string{"CONST"}) {
+		t.Fatalf("Pointed type is wrong: %+v", wchar)
+	}
+	if wchar.Target == nil || wchar.Target.Kind != ntquery.TypePrimitive || wchar.Target.Name != "wchar_t" {
+		t.Errorf("WCHAR is not resolved to primitive: %+v", wchar.Target)
+	}
+
+	node = search.ResolveType("PFOO")
+	if node.Kind != ntquery.TypePointer || node.Target == nil || node.Target.Kind != ntquery.TypeStruct || node.Target.Name != "FOO" {
+		t.Fatalf("PFOO is not a pointer to FOO: %+v", node)
+	}
+	members := node.Target.Members
+	if len(members) != 3 {
+		t.Fatalf("Expected 3 members found %d", len(members))
+	}
+	// Outermost dimension is the first written
+	array := members[0].Type
+	if array.Kind != ntquery.TypeArray || array.Dimension != "MAX_PATH" || array.Name != "WCHAR[MAX_PATH][2]" {
+		t.Errorf("Wrong outer array: %+v", array)
+	} else if inner := array.Target; inner.Kind != ntquery.TypeArray || inner.Dimension != "2" || inner.Name != "WCHAR[2]" ||
+		inner.Target == nil || inner.Target.Name != "WCHAR" {
+		t.Errorf("Wrong inner array: %+v", inner)
+	}
+	if handle := members[1].Type; handle.Kind != ntquery.TypeUnresolved || handle.Name != "HFOO" {
+		t.Errorf("Unknown type is not unresolved: %+v", handle)
+	}
+	if unresolved := node.Unresolved(); !slices.Equal(unresolved, []string{"HFOO"}) {
+		t.Errorf("Wrong unresolved names: %v", unresolved)
+	}
+
+	node = search.ResolveType("PLIST_ENTRY")
+	if node.Kind != ntquery.TypePointer || node.Target == nil || node.Target.Kind != ntquery.TypeStruct || len(node.Target.Members) != 2 {
+		t.Fatalf("PLIST_ENTRY is not a pointer to structure: %+v", node)
+	}
+	for _, member := range node.Target.Members {
+		if member.Type.Kind != ntquery.TypePointer || member.Type.Target == nil || member.Type.Target.Kind != ntquery.TypeCycle ||
+			member.Type.Target.Name != "LIST_ENTRY" {
+			t.Errorf("%s does not point back to LIST_ENTRY: %+v", member.Name, member.Type.Target)
+		}
+	}
+	if unresolved := node.Unresolved(); len(unresolved) != 0 {
+		t.Errorf("Cycle is reported as unresolved: %v", unresolved)
+	}
+
+	if node = search.ResolveType("HANDLE"); node.Kind != ntquery.TypeUnresolved {
+		t.Errorf("win_type without definition is resolved: %+v", node)
+	}
+	if node = search.ResolveType("const NOTHING *"); node.Kind != ntquery.TypePointer || node.Target.Kind != ntquery.TypeUnresolved ||
+		!slices.Equal(node.Unresolved(), []string{"NOTHING"}) {
+		t.Errorf("Unknown name is not unresolved: %+v", node.Target)
+	}
+}
@@ -0,0 +1,84 @@
+// This file refreshes the pages stored in RawHTML with conditional requests, so only the changed pages are replaced
+package main
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/cloakwiss/ntdocs/inter"
+)
+
+type refreshArguments struct {
+	olderThan time.Duration
+	fetcher   inter.FetcherConfig
+}
+
+func parseRefreshArguments(args []string, out *bufio.Writer) (arguments refreshArguments, er error) {
+	flags := flag.NewFlagSet("refresh", flag.ContinueOnError)
+	flags.SetOutput(out)
+	fetcherFlags(flags, &arguments.fetcher)
+	flags.DurationVar(&arguments.olderThan, "older-than", 0, "Refresh only the pages fetched before this long i.e. `72h`, all pages by default")
+	if er = flags.Parse(args); er != nil {
+		return
+	}
+	if flags.NArg() > 0 {
+		return arguments, fmt.Errorf("unexpected arguments: %v", flags.Args())
+	}
+	return
+}
+
+// Fetches the stored pages again with their ETag and Last-Modified. Pages whose main content changed are replaced
+// and the records parsed from them are marked stale. The next fill command drops them, so they are filled again by
+// their own fill command.
+func refreshPages(ctx context.Context, db *sql.DB, stdoutbuf *bufio.Writer, args []string) {
+	arguments, er := parseRefreshArguments(args, stdoutbuf)
+	if errors.Is(er, flag.ErrHelp) {
+		return
+	} else if er != nil {
+		fmt.Fprintln(stdoutbuf, er)
+		return
+	}
+
+	var fetchedBefore time.Time
+	if arguments.olderThan > 0 {
+		fetchedBefore = time.Now().Add(-arguments.olderThan)
+	}
+	pages, er := inter.StoredPages(db, fetchedBefore)
+	if er != nil {
+		log.Panicln(er)
+	}
+	fmt.Fprintln(stdoutbuf, len(pages), "pages to refresh")
+	stdoutbuf.Flush()
+
+	var (
+		refreshed = make(chan inter.RawHTMLRecord)
+		changed   int
+		fetched   int
+	)
+	go inter.FetchPages(ctx, inter.NewFetcher(arguments.fetcher), pages, refreshed)
+	for rec := range refreshed {
+		if rec.Err != nil {
+			continue
+		}
+		fetched++
+		// Fetched pages are stored even when stopped meanwhile
+		isChanged, er := inter.RefreshRawHTML(context.WithoutCancel(ctx), db, rec)
+		if er != nil {
+			log.Panicln(er)
+		}
+		if isChanged {
+			changed++
+			log.Printf("%s changed\n", rec.SymbolName)
+		}
+	}
+	if ctx.Err() != nil {
+		fmt.Fprintln(stdoutbuf, "Stopped, the pages left are not refreshed")
+	}
+	fmt.Fprintln(stdoutbuf, changed, "of", fetched, "refreshed pages changed,", len(pages)-fetched, "could not be fetched")
+}
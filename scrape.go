@@ -0,0 +1,175 @@
+// This file scrapes the pages of Symbol table selected by their type or a filter
+package main
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/cloakwiss/ntdocs/inter"
+)
+
+// Flag which can be given multiple times like `--header memoryapi.h --header fileapi.h`
+type repeated []string
+
+func (r *repeated) String() string {
+	return strings.Join(*r, ", ")
+}
+
+func (r *repeated) Set(value string) error {
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			*r = append(*r, v)
+		}
+	}
+	return nil
+}
+
+// Adds the pages to ScrapeJobs and runs the queue, so a stopped scrape can be continued with resume
+func scrapeRecords(ctx context.Context, db *sql.DB, fetcher *inter.Fetcher, list []inter.SymbolRecord) {
+	if er := inter.EnqueueScrapeJobs(ctx, db, list); er != nil {
+		log.Panicln(er)
+	}
+	runScrapeJobs(ctx, db, fetcher)
+}
+
+// Claims the pending jobs for the workers and stores what they fetch, till no job is pending. All the writes are
+// made here, so the workers never wait on the database. When ctx is done no more jobs are claimed, pages already
+// fetched are still stored and the jobs stopped midway are made pending again for resume.
+func runScrapeJobs(ctx context.Context, db *sql.DB, fetcher *inter.Fetcher) {
+	var (
+		jobs    = make(chan inter.PageRequest)
+		rawHtml = make(chan inter.RawHTMLRecord)
+		// Writes finish even after ctx is done, so the queue matches RawHTML
+		writeCtx = context.WithoutCancel(ctx)
+	)
+	go inter.FetchWorkers(ctx, fetcher, jobs, rawHtml)
+
+	claim := func() (inter.PageRequest, bool) {
+		next, found, er := inter.ClaimScrapeJob(writeCtx, db)
+		if er != nil {
+			log.Panicln(er)
+		}
+		if !found {
+			close(jobs)
+		}
+		return next, found
+	}
+	next, pending := claim()
+	for {
+		// Sending and stopping are disabled by nil channels once the queue is empty
+		send, stopped := jobs, ctx.Done()
+		if !pending {
+			send, stopped = nil, nil
+		}
+		select {
+		case send <- next:
+			next, pending = claim()
+		case <-stopped:
+			if er := inter.ReleaseScrapeJob(writeCtx, db, next.Name); er != nil {
+				log.Panicln(er)
+			}
+			close(jobs)
+			pending = false
+			log.Println("Stopped, the jobs left are scraped by resume")
+		case rec, open := <-rawHtml:
+			if !open {
+				return
+			}
+			if er := inter.CompleteScrapeJob(writeCtx, db, rec); er != nil {
+				log.Panicln(er)
+			}
+		}
+	}
+}
+
+// Continues the jobs left by a stopped scrape, with retryFailed the failed jobs are tried again too
+func resumeScrapeJobs(ctx context.Context, db *sql.DB, stdoutbuf *bufio.Writer, args []string, retryFailed bool) {
+	name, states := "resume", []string{"in-flight"}
+	if retryFailed {
+		name, states = "retry-failed", []string{"in-flight", "failed"}
+	}
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(stdoutbuf)
+	var config inter.FetcherConfig
+	fetcherFlags(flags, &config)
+	if er := flags.Parse(args); errors.Is(er, flag.ErrHelp) {
+		return
+	} else if er != nil || flags.NArg() > 0 {
+		fmt.Fprintln(stdoutbuf, "unexpected arguments:", args)
+		return
+	}
+
+	reset, er := inter.ResetScrapeJobs(ctx, db, states...)
+	if er != nil {
+		log.Panicln(er)
+	}
+	fmt.Fprintln(stdoutbuf, reset, "jobs made pending again")
+	stdoutbuf.Flush()
+	runScrapeJobs(ctx, db, inter.NewFetcher(config))
+
+	counts, er := inter.ScrapeJobCounts(db)
+	if er != nil {
+		log.Panicln(er)
+	}
+	fmt.Fprintf(stdoutbuf, "%d jobs done, %d failed, %d pending\n", counts["done"], counts["failed"], counts["pending"])
+}
+
+// Options of the fetcher for the commands which take arguments, the defaults are of inter.DefaultFetcherConfig
+func fetcherFlags(flags *flag.FlagSet, config *inter.FetcherConfig) {
+	*config = inter.DefaultFetcherConfig
+	flags.Float64Var(&config.Rate, "rate", config.Rate, "Requests per second to a host")
+	flags.IntVar(&config.Burst, "burst", config.Burst, "Requests which can be made at once after being idle")
+	flags.IntVar(&config.Workers, "workers", config.Workers, "Pages fetched at the same time")
+	flags.DurationVar(&config.Timeout, "timeout", config.Timeout, "Limit for a single request")
+	flags.IntVar(&config.MaxRetries, "retries", config.MaxRetries, "Retries of a failed request")
+	flags.DurationVar(&config.MaxBackoff, "max-backoff", config.MaxBackoff, "Longest wait before a retry, also limits Retry-After")
+}
+
+type scrapeArguments struct {
+	inter.SymbolFilter
+	fetcher inter.FetcherConfig
+}
+
+func parseScrapeArguments(args []string, out *bufio.Writer) (arguments scrapeArguments, er error) {
+	flags := flag.NewFlagSet("scrape", flag.ContinueOnError)
+	flags.SetOutput(out)
+	fetcherFlags(flags, &arguments.fetcher)
+	flags.Var((*repeated)(&arguments.Types), "type", "Type of symbol as in Symbol table i.e. `function`, can be repeated or comma separated")
+	flags.StringVar(&arguments.Where, "where", "", "SQL condition on the columns of Symbol table i.e. `header = 'memoryapi.h'`")
+	if er = flags.Parse(args); er != nil {
+		return
+	}
+	if flags.NArg() > 0 {
+		return arguments, fmt.Errorf("unexpected arguments: %v", flags.Args())
+	}
+	if len(arguments.Types) == 0 && arguments.Where == "" {
+		return arguments, fmt.Errorf("no filter given, use -type or -where")
+	}
+	return
+}
+
+// Scrapes the pages of the selected symbols which are not scraped yet
+func scrapeSymbols(ctx context.Context, db *sql.DB, stdoutbuf *bufio.Writer, args []string) {
+	arguments, er := parseScrapeArguments(args, stdoutbuf)
+	if errors.Is(er, flag.ErrHelp) {
+		return
+	} else if er != nil {
+		fmt.Fprintln(stdoutbuf, er)
+		return
+	}
+
+	list, er := inter.FilteredSymbols(db, arguments.SymbolFilter)
+	if er != nil {
+		fmt.Fprintln(stdoutbuf, er)
+		return
+	}
+	fmt.Fprintln(stdoutbuf, len(list), "symbols to scrape")
+	stdoutbuf.Flush()
+	scrapeRecords(ctx, db, inter.NewFetcher(arguments.fetcher), list)
+}
@@ -0,0 +1,95 @@
+// This file scrapes a subset of the Symbol table selected by headers, DLLs or a list of symbols
+package main
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/cloakwiss/ntdocs/inter"
+)
+
+type seedArguments struct {
+	inter.Seeds
+	symbolFile       string
+	withDependencies bool
+	fetcher          inter.FetcherConfig
+}
+
+func parseSeedArguments(args []string, out *bufio.Writer) (seeds seedArguments, er error) {
+	flags := flag.NewFlagSet("scrape-seed", flag.ContinueOnError)
+	flags.SetOutput(out)
+	fetcherFlags(flags, &seeds.fetcher)
+	flags.Var((*repeated)(&seeds.Headers), "header", "Header as in Headers table i.e. `memoryapi.h`, can be repeated or comma separated")
+	flags.Var((*repeated)(&seeds.DLLs), "dll", "DLL as in the requirements of filled pages i.e. `kernel32.dll`, can be repeated or comma separated")
+	flags.StringVar(&seeds.symbolFile, "symbols", "", "File with a symbol name on each line, lines starting with # are skipped")
+	flags.BoolVar(&seeds.withDependencies, "with-dependencies", false, "Fill the seeds and scrape the types referenced by them till no new type is found")
+	if er = flags.Parse(args); er != nil {
+		return
+	}
+	if flags.NArg() > 0 {
+		return seeds, fmt.Errorf("unexpected arguments: %v", flags.Args())
+	}
+
+	if seeds.symbolFile != "" {
+		if seeds.Names, er = readSymbolFile(seeds.symbolFile); er != nil {
+			return
+		}
+	}
+	if len(seeds.Headers) == 0 && len(seeds.DLLs) == 0 && len(seeds.Names) == 0 {
+		return seeds, fmt.Errorf("no seed given, use -header, -dll or -symbols")
+	}
+	return
+}
+
+func readSymbolFile(path string) (names []string, er error) {
+	file, er := os.Open(path)
+	if er != nil {
+		return nil, fmt.Errorf("cannot open the symbol file: %w", er)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		names = append(names, line)
+	}
+	return names, scanner.Err()
+}
+
+// Scrapes the pages of seeds which are not scraped yet. With dependencies the seeds are filled and the closure is
+// scraped as by scrape-closure, for a database built only from the seeds these are the dependencies of seeds.
+func scrapeSeeds(ctx context.Context, db *sql.DB, stdoutbuf *bufio.Writer, args []string) {
+	seeds, er := parseSeedArguments(args, stdoutbuf)
+	if errors.Is(er, flag.ErrHelp) {
+		return
+	} else if er != nil {
+		fmt.Fprintln(stdoutbuf, er)
+		return
+	}
+
+	fetcher := inter.NewFetcher(seeds.fetcher)
+	list := inter.SeedSymbols(db, seeds.Seeds)
+	fmt.Fprintln(stdoutbuf, len(list), "symbols to scrape")
+	stdoutbuf.Flush()
+	scrapeRecords(ctx, db, fetcher, list)
+
+	if !seeds.withDependencies || ctx.Err() != nil {
+		return
+	}
+	log.Println("Filling the seeds")
+	fillFunctionRecords(ctx, db, stdoutbuf)
+	fillCallbackRecords(ctx, db, stdoutbuf)
+	fillStructureRecords(ctx, db, stdoutbuf)
+	fillEnumerationRecords(ctx, db, stdoutbuf)
+	scrapeClosure(ctx, db, fetcher, stdoutbuf)
+}
@@ -0,0 +1,158 @@
+// Contains the function to create CallbackDeclaration struct
+package callback
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+
+	"github.com/cloakwiss/ntdocs/symbols/function"
+	"github.com/cloakwiss/ntdocs/utils"
+)
+
+// Callback pages show the syntax in one of these two forms:
+//
+//	typedef LRESULT (CALLBACK *HOOKPROC)(int code, WPARAM wParam, LPARAM lParam);
+//
+// or a declaration of an application defined function using the typedef:
+//
+//	PTP_WIN32_IO_CALLBACK PtpWin32IoCallback;
+//
+//	void PtpWin32IoCallback(
+//	  [in, out] PTP_CALLBACK_INSTANCE Instance,
+//	  ...
+//	)
+//	{...}
+type CallbackDeclaration struct {
+	// Name of the typedef, this is the name found in Symbol table
+	TypedefName, CallingConvention string
+	// True for `typedef RET (CALLBACK *NAME)(...)`, false when typedef is of the function type itself
+	IsPointer bool
+	// Name here is of the placeholder function, same as TypedefName when page does not show one
+	function.FunctionDeclaration
+}
+
+// This type will be used to match the schema of database, same as function's
+type CallbackDeclarationForInsertion struct {
+	CallbackDeclaration
+	Description, Requirements string
+	ParsedRequirements        utils.Requirements
+	ParameterDescription      utils.AssociativeArray[string, []string]
+	ParameterConstants        utils.AssociativeArray[string, []utils.ValueDefinition]
+	RenderedDescription       utils.Rendered
+	RenderedParameters        []utils.Rendered
+}
+
+var (
+	ErrorSomeNewNode     = errors.New("Some new node")
+	ErrorNoCallbackFound = errors.New("No callback found in syntax block")
+)
+
+func getString(node *tree_sitter.Node, code []byte) string {
+	return string(code[node.StartByte():node.EndByte()])
+}
+
+func HandleSyntaxSection(parser *tree_sitter.Parser, syntax string) (CallbackDeclaration, error) {
+	var (
+		prepared    = function.PrepareSyntax(syntax)
+		code        = prepared.Code
+		hints       = prepared.UsageHints
+		conventions = prepared.CallingConventions
+		decl        CallbackDeclaration
+	)
+	tree := parser.Parse(code, nil)
+	defer tree.Close()
+	rootNode := tree.RootNode()
+
+	var (
+		typedefFound bool
+		functionNode *tree_sitter.Node
+	)
+	for _, node := range rootNode.NamedChildren(rootNode.Walk()) {
+		switch node.Kind() {
+		case "type_definition":
+			declarator, stars := unwrapPointers(node.ChildByFieldName("declarator"))
+			if declarator == nil || declarator.Kind() != "function_declarator" {
+				return CallbackDeclaration{}, fmt.Errorf("%w : %s", ErrorSomeNewNode, getString(&node, code))
+			}
+			decl.ReturnType = getString(node.ChildByFieldName("type"), code) + stars
+
+			// `(NTAPI *PX)` is a pointer to function, `(NTAPI X)` left after the calling convention is not
+			inner := declarator.ChildByFieldName("declarator")
+			decl.IsPointer = inner.Kind() == "parenthesized_declarator" && containsPointer(inner)
+			name := function.DeclaratorName(inner)
+			if name == nil {
+				return CallbackDeclaration{}, ErrorNoCallbackFound
+			}
+			decl.TypedefName = getString(name, code)
+			decl.Name = decl.TypedefName
+			decl.Parameters = function.HandleParameterList(declarator.ChildByFieldName("parameters"), code, hints)
+			typedefFound = true
+
+		// `PTP_WIN32_IO_CALLBACK PtpWin32IoCallback;`
+		case "declaration":
+			declarator := node.ChildByFieldName("declarator")
+			if declarator != nil && declarator.Kind() == "identifier" {
+				decl.TypedefName = getString(node.ChildByFieldName("type"), code)
+				decl.Name = getString(declarator, code)
+				// Typedef itself is not shown, these are declared as pointer to function in headers
+				decl.IsPointer = true
+				continue
+			}
+			functionNode = &node
+
+		case "function_definition":
+			functionNode = &node
+
+		case "comment", "ERROR":
+		default:
+			return CallbackDeclaration{}, fmt.Errorf("%w : %s", ErrorSomeNewNode, node.Kind())
+		}
+	}
+
+	if !typedefFound {
+		if functionNode == nil || decl.TypedefName == "" {
+			return CallbackDeclaration{}, ErrorNoCallbackFound
+		}
+		declarator, stars := unwrapPointers(functionNode.ChildByFieldName("declarator"))
+		if declarator == nil || declarator.Kind() != "function_declarator" {
+			return CallbackDeclaration{}, fmt.Errorf("%w : %s", ErrorSomeNewNode, getString(functionNode, code))
+		}
+		decl.ReturnType = getString(functionNode.ChildByFieldName("type"), code) + stars
+		decl.Parameters = function.HandleParameterList(declarator.ChildByFieldName("parameters"), code, hints)
+	}
+
+	if len(conventions) > 0 {
+		decl.CallingConvention = conventions[0].Text
+	}
+	decl.Arity = uint8(len(decl.Parameters))
+	return decl, nil
+}
+
+// Tells if there is a pointer declarator within the parentheses
+func containsPointer(node *tree_sitter.Node) bool {
+	if node.Kind() == "pointer_declarator" {
+		return true
+	}
+	for _, child := range node.NamedChildren(node.Walk()) {
+		if containsPointer(&child) {
+			return true
+		}
+	}
+	return false
+}
+
+// Goes through the pointer declarators wrapping the function declarator, these belong to the return type
+func unwrapPointers(node *tree_sitter.Node) (*tree_sitter.Node, string) {
+	var depth int
+	for node != nil && node.Kind() == "pointer_declarator" {
+		depth += 1
+		node = node.ChildByFieldName("declarator")
+	}
+	if depth > 0 {
+		return node, " " + strings.Repeat("*", depth)
+	}
+	return node, ""
+}
@@ -0,0 +1,103 @@
+package callback_test
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+
+	"github.com/k0kubun/pp/v3"
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+	tree_sitter_c "github.com/tree-sitter/tree-sitter-c/bindings/go"
+
+	"github.com/cloakwiss/ntdocs/symbols/callback"
+	"github.com/cloakwiss/ntdocs/symbols/function"
+	"github.com/cloakwiss/ntdocs/utils"
+)
+
+func TestCallback(t *testing.T) {
+	var data string = `<div class="content"><p>An application-defined callback function used with the activity coordinator API.</p>
+<h2 id="syntax">Syntax</h2>
+<pre><code class="lang-cpp">ACTIVITY_COORDINATOR_CALLBACK ActivityCoordinatorCallback;
+
+void ActivityCoordinatorCallback(
+  [in]           ACTIVITY_COORDINATOR_NOTIFICATION notification,
+  [in, optional] void *callbackContext
+)
+{...}
+</code></pre>
+<h2 id="parameters">Parameters</h2>
+<p><code>[in] notification</code></p>
+<p>The notification sent to the callback.</p>
+<p><code>[in, optional] callbackContext</code></p>
+<p>The context passed when the callback was registered.</p>
+<h2 id="return-value">Return value</h2>
+<p>None</p>
+</div>`
+
+	backing := strings.NewReader(data)
+	buffer := bufio.NewReader(backing)
+	mainContent := utils.GetMainContent(buffer)
+	content := utils.GetAllSection(mainContent)
+
+	parser := tree_sitter.NewParser()
+	defer parser.Close()
+	parser.SetLanguage(tree_sitter.NewLanguage(tree_sitter_c.Language()))
+
+	decl, er := callback.HandleSyntaxSection(parser, content["syntax"][0].Text())
+	if er != nil {
+		t.Fatal(er)
+	}
+	pp.Println(decl)
+
+	if decl.TypedefName != "ACTIVITY_COORDINATOR_CALLBACK" || decl.Name != "ActivityCoordinatorCallback" || decl.ReturnType != "void" {
+		t.Fatalf("Wrong signature: %+v", decl)
+	}
+	expected := []function.Parameter{
+		{UsageHint: "in", TypeHint: "ACTIVITY_COORDINATOR_NOTIFICATION", Name: "notification",
+			Usage: function.Usage{Direction: function.DirectionIn}},
+		{UsageHint: "in, optional", TypeHint: "void *", Name: "callbackContext", PointerDepth: 1,
+			Usage: function.Usage{Direction: function.DirectionIn, Optional: true}},
+	}
+	if int(decl.Arity) != len(expected) {
+		t.Fatalf("Expected %d parameters found %d", len(expected), decl.Arity)
+	}
+	for i := range expected {
+		if decl.Parameters[i] != expected[i] {
+			t.Errorf("Parameter %d: expected %+v found %+v", i, expected[i], decl.Parameters[i])
+		}
+	}
+
+	paras, er := function.HandleParameterSectionOfFunction(content["parameters"])
+	if er != nil || len(paras) != int(decl.Arity) {
+		t.Fatalf("Parameter documentation mismatch: %v", er)
+	}
+}
+
+func TestCallbackTypedef(t *testing.T) {
+	parser := tree_sitter.NewParser()
+	defer parser.Close()
+	parser.SetLanguage(tree_sitter.NewLanguage(tree_sitter_c.Language()))
+
+	decl, er := callback.HandleSyntaxSection(parser, "typedef LRESULT (CALLBACK *HOOKPROC)(int code, WPARAM wParam, LPARAM lParam);")
+	if er != nil {
+		t.Fatal(er)
+	}
+	if decl.TypedefName != "HOOKPROC" || !decl.IsPointer || decl.CallingConvention != "CALLBACK" || decl.ReturnType != "LRESULT" || decl.Arity != 3 {
+		t.Fatalf("Wrong signature: %+v", decl)
+	}
+
+	decl, er = callback.HandleSyntaxSection(parser, "typedef VOID NTAPI WAITORTIMERCALLBACKFUNC(PVOID, BOOLEAN);")
+	if er != nil {
+		t.Fatal(er)
+	}
+	if decl.TypedefName != "WAITORTIMERCALLBACKFUNC" || decl.IsPointer || decl.CallingConvention != "NTAPI" || decl.Arity != 2 {
+		t.Fatalf("Wrong signature: %+v", decl)
+	}
+	decl, er = callback.HandleSyntaxSection(parser, "typedef VOID (NTAPI WAITORTIMERCALLBACKFUNC)(PVOID, BOOLEAN);")
+	if er != nil {
+		t.Fatal(er)
+	}
+	if decl.TypedefName != "WAITORTIMERCALLBACKFUNC" || decl.IsPointer || decl.CallingConvention != "NTAPI" || decl.Arity != 2 {
+		t.Fatalf("Wrong signature: %+v", decl)
+	}
+}
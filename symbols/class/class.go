@@ -0,0 +1,228 @@
+// Contains the function to create ClassDeclaration struct from `nl-` pages, these are the C++ classes of GDI+ like
+// `nl-gdiplusimaging-bitmapdata`
+package class
+
+import (
+	"errors"
+	"log"
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+
+	"github.com/cloakwiss/ntdocs/symbols/structure"
+	"github.com/cloakwiss/ntdocs/utils"
+)
+
+type ClassDeclaration struct {
+	Name string
+	// Direct base classes from the syntax, or from the Inheritance section when syntax does not show them
+	BaseClasses []string
+	// Data members from the syntax, methods declared in the syntax are not kept here. Empty when any of them cannot
+	// be parsed as C
+	Members []structure.Field
+	// In the order of Methods section of the page, constructors and destructor are included
+	Methods []Method
+}
+
+type MethodKind string
+
+const (
+	KindMethod      MethodKind = "method"
+	KindConstructor MethodKind = "constructor"
+	KindDestructor  MethodKind = "destructor"
+	KindOperator    MethodKind = "operator"
+)
+
+type Method struct {
+	// Name without the class i.e. `GetPixel` for `Bitmap::GetPixel`
+	Name string
+	// Parameter list shown for the overloads i.e. `(constBITMAPINFO*,VOID*)`, empty when page does not show one
+	Overload string
+	Kind     MethodKind
+	// Inner html of the description cell
+	Description string
+	// Link as written in the page, Path is the resolved form of it as in Symbol table
+	Href, Path string
+}
+
+// This type will be used to match the schema of database
+type ClassDeclarationForInsertion struct {
+	ClassDeclaration
+	Description, Requirements string
+	ParsedRequirements        utils.Requirements
+	RenderedDescription       utils.Rendered
+}
+
+var (
+	ErrorNoClassFound   = errors.New("No class found in syntax block")
+	ErrorNoMethodsFound = errors.New("No methods found in the class page")
+
+	classPattern       = regexp.MustCompile(`(?s)\b(?:class|struct)\s+(\w+)\s*(?::\s*([^{]*))?\{(.*)\}`)
+	accessPattern      = regexp.MustCompile(`\b(?:public|protected|private)\s*:`)
+	commentPattern     = regexp.MustCompile(`(?s)//[^\n]*|/\*.*?\*/`)
+	inheritancePattern = regexp.MustCompile(`(?:inherits from|implements) (?:the )?(\w+)`)
+	methodPattern      = regexp.MustCompile(`^(?:(\w+)::)?(~?\w+|operator\s*[^\s(]+)\s*(\(.*\))?`)
+)
+
+// Name is the name of Symbol as the heading is not kept by the scraper, base is the path of the page for resolving links
+func HandleClassPage(parser *tree_sitter.Parser, name, base string, content map[string][]*goquery.Selection) (decl ClassDeclaration, err error) {
+	decl.Name = name
+
+	if len(content["syntax"]) == 1 {
+		if decl, err = HandleSyntaxSection(parser, content["syntax"][0].Text()); err != nil {
+			return ClassDeclaration{}, err
+		}
+		// Name of Symbol is the one used for the record
+		decl.Name = name
+	}
+
+	if len(decl.BaseClasses) == 0 {
+		var texts []string
+		for _, block := range content["inheritance"] {
+			texts = append(texts, block.Text())
+		}
+		text := strings.Join(strings.Fields(strings.Join(texts, " ")), " ")
+		// Classes without any base say that they implement themselves
+		if match := inheritancePattern.FindStringSubmatch(text); match != nil && match[1] != name {
+			decl.BaseClasses = []string{match[1]}
+		}
+	}
+
+	for _, section := range []string{"constructors", "methods"} {
+		for _, block := range content[section] {
+			for _, table := range block.Filter("table").AddSelection(block.Find("table")).EachIter() {
+				for _, row := range table.Find("tr").EachIter() {
+					cells := row.ChildrenFiltered("td")
+					if cells.Length() < 1 {
+						continue
+					}
+					method, found := parseMethodName(name, cells.Eq(0).Text())
+					if !found {
+						continue
+					}
+					if href, found := cells.Eq(0).Find("a").First().Attr("href"); found {
+						method.Href = href
+						method.Path, _ = utils.ResolveLink(base, href)
+					}
+					if cells.Length() > 1 {
+						if htm, er := cells.Eq(1).Html(); er == nil {
+							method.Description = strings.TrimSpace(htm)
+						}
+					}
+					decl.Methods = append(decl.Methods, method)
+				}
+			}
+		}
+	}
+	if len(decl.Methods) == 0 && len(content["methods"]) > 0 {
+		err = ErrorNoMethodsFound
+	}
+	return
+}
+
+// Text of the methods table cell like `Bitmap::Bitmap(constBITMAPINFO*,VOID*)`, class is optional in the text
+func parseMethodName(className, text string) (Method, bool) {
+	match := methodPattern.FindStringSubmatch(strings.TrimSpace(text))
+	if match == nil {
+		return Method{}, false
+	}
+	method := Method{
+		Name:     strings.Join(strings.Fields(match[2]), ""),
+		Overload: strings.Join(strings.Fields(match[3]), ""),
+		Kind:     KindMethod,
+	}
+	switch {
+	case method.Name == className:
+		method.Kind = KindConstructor
+	case method.Name == "~"+className:
+		method.Kind = KindDestructor
+	case strings.HasPrefix(method.Name, "operator"):
+		method.Kind = KindOperator
+	}
+	return method, true
+}
+
+// Syntax of the class is written in C++:
+//
+//	class BitmapData {
+//	public:
+//	  UINT        Width;
+//	  ...
+//	};
+//
+// It is reduced to a C struct with only the data members, so that it can be parsed like a structure.
+func HandleSyntaxSection(parser *tree_sitter.Parser, syntax string) (ClassDeclaration, error) {
+	match := classPattern.FindStringSubmatch(commentPattern.ReplaceAllString(syntax, ""))
+	if match == nil {
+		return ClassDeclaration{}, ErrorNoClassFound
+	}
+	decl := ClassDeclaration{Name: match[1]}
+
+	for _, base := range strings.Split(match[2], ",") {
+		// Last word after access specifier and `virtual`
+		if words := strings.Fields(base); len(words) > 0 {
+			decl.BaseClasses = append(decl.BaseClasses, words[len(words)-1])
+		}
+	}
+
+	members := dataMembers(accessPattern.ReplaceAllString(match[3], ""))
+	if len(members) == 0 {
+		return decl, nil
+	}
+	code := []byte("typedef struct " + decl.Name + " {\n" + strings.Join(members, ";\n") + ";\n} " + decl.Name + ";")
+	tree := parser.Parse(code, nil)
+	defer tree.Close()
+	data, er := structure.HandleSyntaxSection(tree, code)
+	if er != nil {
+		// Members only C++ has like `static const int Max = 5;` or `std::vector<int> v;`, rest of the class is kept
+		log.Println("Members of", decl.Name, "are not parsed:", er)
+		return decl, nil
+	}
+	decl.Members = data.Fields
+	return decl, nil
+}
+
+// Splits the body of class in declarations, the ones with parameter list (methods, constructors and operators)
+// are dropped with their inline body
+func dataMembers(body string) (members []string) {
+	var (
+		current strings.Builder
+		depth   int
+	)
+	flush := func() {
+		statement := strings.TrimSpace(current.String())
+		current.Reset()
+		if statement == "" || strings.Contains(statement, "(") {
+			return
+		}
+		switch strings.Fields(statement)[0] {
+		case "friend", "using", "typedef":
+			return
+		}
+		members = append(members, statement)
+	}
+	for _, char := range body {
+		switch {
+		case char == '{':
+			depth += 1
+		case char == '}':
+			depth -= 1
+			// End of inline body of a method, nested aggregates continue till the `;`
+			if depth == 0 && strings.Contains(current.String(), "(") {
+				current.Reset()
+				continue
+			}
+		case char == ';' && depth == 0:
+			flush()
+			continue
+		}
+		if depth > 0 && strings.Contains(current.String(), "(") {
+			continue
+		}
+		current.WriteRune(char)
+	}
+	flush()
+	return
+}
@@ -0,0 +1,119 @@
+package class_test
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+	tree_sitter_c "github.com/tree-sitter/tree-sitter-c/bindings/go"
+
+	"github.com/cloakwiss/ntdocs/symbols/class"
+	"github.com/cloakwiss/ntdocs/utils"
+)
+
+func TestClass(t *testing.T) {
+	var data string = `<div class="content"><p>The <b>Bitmap</b> class expands on the capabilities of the Image class.</p>
+<h2 id="syntax">Syntax</h2>
+<pre><code class="lang-cpp">class Bitmap : public Image {
+public:
+  Bitmap(const BITMAPINFO *gdiBitmapInfo, VOID *gdiBitmapData);
+  ~Bitmap() { }
+  Status GetPixel(INT x, INT y, Color *color);
+  Bitmap &amp;operator=(const Bitmap &amp;);
+protected:
+  // Owned by the object
+  GpBitmap *nativeBitmap;
+  UINT     flags : 4;
+  WCHAR    name[MAX_PATH];
+};</code></pre>
+<h2 id="inheritance">Inheritance</h2>
+<p>The <b>Bitmap</b> class implements <a href="nl-gdiplusheaders-image" data-linktype="relative-path">Image</a>.</p>
+<h2 id="methods">Methods</h2>
+<table>
+<tr><th>Method</th><th>Description</th></tr>
+<tr><td><a href="nf-gdiplusheaders-bitmap-bitmap(constbitmapinfo_void)" data-linktype="relative-path">Bitmap::Bitmap(constBITMAPINFO*,VOID*)</a></td><td>Creates a <b>Bitmap</b> object.</td></tr>
+<tr><td><a href="nf-gdiplusheaders-bitmap-getpixel" data-linktype="relative-path">Bitmap::GetPixel</a></td><td>Gets the color of a pixel.</td></tr>
+<tr><td>Bitmap::~Bitmap</td><td>Releases the object.</td></tr>
+</table>
+</div>`
+
+	parser := tree_sitter.NewParser()
+	defer parser.Close()
+	parser.SetLanguage(tree_sitter.NewLanguage(tree_sitter_c.Language()))
+
+	content := utils.GetAllSection(utils.GetMainContent(bufio.NewReader(strings.NewReader(data))))
+	decl, er := class.HandleClassPage(parser, "Bitmap", "/windows/win32/api/gdiplusheaders/nl-gdiplusheaders-bitmap", content)
+	if er != nil {
+		t.Fatal(er)
+	}
+	if len(decl.BaseClasses) != 1 || decl.BaseClasses[0] != "Image" {
+		t.Errorf("Wrong base classes: %v", decl.BaseClasses)
+	}
+	if len(decl.Members) != 3 || decl.Members[0].Name != "nativeBitmap" || decl.Members[0].Datatype != "GpBitmap *" ||
+		decl.Members[1].BitWidth != "4" || decl.Members[2].DimensionSuffix() != "[MAX_PATH]" {
+		t.Errorf("Wrong members: %+v", decl.Members)
+	}
+
+	if len(decl.Methods) != 3 {
+		t.Fatalf("Wrong methods: %+v", decl.Methods)
+	}
+	constructor := decl.Methods[0]
+	if constructor.Name != "Bitmap" || constructor.Kind != class.KindConstructor || constructor.Overload != "(constBITMAPINFO*,VOID*)" ||
+		constructor.Path != "/windows/win32/api/gdiplusheaders/nf-gdiplusheaders-bitmap-bitmap(constbitmapinfo_void)" ||
+		constructor.Description != "Creates a <b>Bitmap</b> object." {
+		t.Errorf("Wrong constructor: %+v", constructor)
+	}
+	if decl.Methods[1].Name != "GetPixel" || decl.Methods[1].Kind != class.KindMethod || decl.Methods[1].Overload != "" {
+		t.Errorf("Wrong method: %+v", decl.Methods[1])
+	}
+	if decl.Methods[2].Name != "~Bitmap" || decl.Methods[2].Kind != class.KindDestructor || decl.Methods[2].Href != "" {
+		t.Errorf("Wrong destructor: %+v", decl.Methods[2])
+	}
+}
+
+func TestClassInheritance(t *testing.T) {
+	var data string = `<div class="content"><p>A <b>BitmapData</b> object stores attributes of a bitmap.</p>
+<h2 id="inheritance">Inheritance</h2>
+<p>The <b>BitmapData</b> class implements <b>BitmapData</b>.</p>
+</div>`
+
+	content := utils.GetAllSection(utils.GetMainContent(bufio.NewReader(strings.NewReader(data))))
+	decl, er := class.HandleClassPage(nil, "BitmapData", "/windows/win32/api/gdiplusimaging/nl-gdiplusimaging-bitmapdata", content)
+	if er != nil {
+		t.Fatal(er)
+	}
+	if len(decl.BaseClasses) != 0 || len(decl.Methods) != 0 {
+		t.Errorf("Wrong class: %+v", decl)
+	}
+}
+
+func TestClassCppMembers(t *testing.T) {
+	parser := tree_sitter.NewParser()
+	defer parser.Close()
+	parser.SetLanguage(tree_sitter.NewLanguage(tree_sitter_c.Language()))
+
+	for _, member := range []string{"static const int Max = 5;", "Gdiplus::Status s;", "std::vector<int> v;"} {
+		data := `<div class="content"><h2 id="syntax">Syntax</h2>
+<pre><code class="lang-cpp">class Region : public GdiplusBase {
+public:
+  Region();
+  ` + member + `
+  INT count;
+};</code></pre>
+<h2 id="methods">Methods</h2>
+<table>
+<tr><th>Method</th><th>Description</th></tr>
+<tr><td>Region::Region</td><td>Creates a region.</td></tr>
+</table>
+</div>`
+		content := utils.GetAllSection(utils.GetMainContent(bufio.NewReader(strings.NewReader(data))))
+		decl, er := class.HandleClassPage(parser, "Region", "/windows/win32/api/gdiplusheaders/nl-gdiplusheaders-region", content)
+		if er != nil {
+			t.Fatalf("%s: %v", member, er)
+		}
+		if len(decl.Members) != 0 || len(decl.Methods) != 1 || len(decl.BaseClasses) != 1 || decl.BaseClasses[0] != "GdiplusBase" {
+			t.Errorf("%s: wrong class %+v", member, decl)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+// Contains the function to create InterfaceDeclaration struct from `nn-` pages and MethodDeclaration from the `nf-` pages
+// of its methods
+package cominterface
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+
+	"github.com/cloakwiss/ntdocs/symbols/function"
+	"github.com/cloakwiss/ntdocs/utils"
+)
+
+type InterfaceDeclaration struct {
+	Name string
+	// Empty when the page does not mention any GUID
+	IID string
+	// Empty only for IUnknown
+	BaseInterface string
+	// In the order of Methods section of the page, which is alphabetical and not the order of declaration. Methods
+	// of base interface are not included
+	Methods []Method
+}
+
+type Method struct {
+	// Name without the interface i.e. `CreateInstance` for `IClassFactory::CreateInstance`
+	Name string
+	// Inner html of the description cell
+	Description string
+	// Link as written in the page, Path is the resolved form of it as in Symbol table
+	Href, Path string
+}
+
+// Name of RawHTML record of the method page, same as shown in the page i.e. `IClassFactory::CreateInstance`
+func (m Method) SymbolName(interfaceName string) string {
+	return interfaceName + "::" + m.Name
+}
+
+// This type will be used to match the schema of database
+type InterfaceDeclarationForInsertion struct {
+	InterfaceDeclaration
+	Description, Requirements string
+	ParsedRequirements        utils.Requirements
+	RenderedDescription       utils.Rendered
+}
+
+// Signature of the method, shown in the page same as a function
+type MethodDeclaration struct {
+	Interface string
+	function.FunctionDeclaration
+}
+
+// This type will be used to match the schema of database, same as function's
+type MethodDeclarationForInsertion struct {
+	MethodDeclaration
+	Description          string
+	ParameterDescription utils.AssociativeArray[string, []string]
+	RenderedParameters   []utils.Rendered
+	ReturnValue          function.ReturnValue
+}
+
+var (
+	ErrorNoMethodsFound = errors.New("No methods found in the interface page")
+
+	guidPattern        = regexp.MustCompile(`\b[0-9A-Fa-f]{8}-[0-9A-Fa-f]{4}-[0-9A-Fa-f]{4}-[0-9A-Fa-f]{4}-[0-9A-Fa-f]{12}\b`)
+	inheritancePattern = regexp.MustCompile(`inherits from (?:the )?(\w+)(?: interface)?`)
+	methodNamePattern  = regexp.MustCompile(`^(\w+)::(\w+)`)
+)
+
+// Splits `IClassFactory::CreateInstance` into interface and method
+func SplitMethodName(text string) (interfaceName, methodName string, ok bool) {
+	match := methodNamePattern.FindStringSubmatch(strings.TrimSpace(text))
+	if match == nil {
+		return "", "", false
+	}
+	return match[1], match[2], true
+}
+
+// Name is the name of Symbol as the heading is not kept by the scraper, base is the path of the page for resolving links
+func HandleInterfacePage(name, base string, content map[string][]*goquery.Selection) (decl InterfaceDeclaration, err error) {
+	decl.Name = name
+
+	var texts []string
+	for _, section := range []string{"basic-description", "inheritance", "remarks"} {
+		for _, block := range content[section] {
+			texts = append(texts, block.Text())
+		}
+	}
+	text := strings.Join(strings.Fields(strings.Join(texts, " ")), " ")
+	if match := inheritancePattern.FindStringSubmatch(text); match != nil {
+		decl.BaseInterface = match[1]
+	}
+	if iid := guidPattern.FindString(text); iid != "" {
+		decl.IID = strings.ToUpper(iid)
+	}
+
+	for _, block := range content["methods"] {
+		for _, table := range block.Filter("table").AddSelection(block.Find("table")).EachIter() {
+			for _, row := range table.Find("tr").EachIter() {
+				cells := row.ChildrenFiltered("td")
+				if cells.Length() < 1 {
+					continue
+				}
+				anchor := cells.Eq(0).Find("a").First()
+				_, methodName, found := SplitMethodName(cells.Eq(0).Text())
+				if !found {
+					// Some pages only have the name of method without the interface
+					methodName = strings.TrimSpace(cells.Eq(0).Text())
+				}
+				if methodName == "" {
+					continue
+				}
+				method := Method{Name: methodName}
+				if href, found := anchor.Attr("href"); found {
+					method.Href = href
+					method.Path, _ = utils.ResolveLink(base, href)
+				}
+				if cells.Length() > 1 {
+					if htm, er := cells.Eq(1).Html(); er == nil {
+						method.Description = strings.TrimSpace(htm)
+					}
+				}
+				decl.Methods = append(decl.Methods, method)
+			}
+		}
+	}
+	if len(decl.Methods) == 0 && len(content["methods"]) > 0 {
+		err = ErrorNoMethodsFound
+	}
+	return
+}
+
+// Syntax of method page is same as of function i.e. `HRESULT CreateInstance([in] IUnknown *pUnkOuter, ...);`
+func HandleMethodSyntax(parser *tree_sitter.Parser, interfaceName string, syntax string) (MethodDeclaration, error) {
+	signature, er := function.HandleFunctionSyntax(parser, syntax)
+	if er != nil {
+		return MethodDeclaration{}, er
+	}
+	// Few pages write the name as `IClassFactory::CreateInstance`
+	if _, methodName, found := SplitMethodName(signature.Name); found {
+		signature.Name = methodName
+	}
+	return MethodDeclaration{Interface: interfaceName, FunctionDeclaration: signature}, nil
+}
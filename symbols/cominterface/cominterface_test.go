@@ -0,0 +1,65 @@
+package cominterface_test
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+	tree_sitter_c "github.com/tree-sitter/tree-sitter-c/bindings/go"
+
+	"github.com/cloakwiss/ntdocs/symbols/cominterface"
+	"github.com/cloakwiss/ntdocs/utils"
+)
+
+func TestInterface(t *testing.T) {
+	var data string = `<div class="content"><p>Enables a class of objects to be created.</p>
+<h2 id="inheritance">Inheritance</h2>
+<p>The <b>IClassFactory</b> interface inherits from the <a href="nn-unknwn-iunknown" data-linktype="relative-path">IUnknown</a> interface. <b>IClassFactory</b> also has these types of members:</p>
+<h2 id="methods">Methods</h2>
+<p>The <b>IClassFactory</b> interface has these methods.</p>
+<table>
+<thead><tr><th>Method</th><th>Description</th></tr></thead>
+<tbody>
+<tr><td><a href="nf-unknwn-iclassfactory-createinstance" data-linktype="relative-path">IClassFactory::CreateInstance</a></td><td>Creates an uninitialized object.</td></tr>
+<tr><td><a href="nf-unknwn-iclassfactory-lockserver" data-linktype="relative-path">IClassFactory::LockServer</a></td><td>Locks an object application open in memory.</td></tr>
+</tbody>
+</table>
+<h2 id="remarks">Remarks</h2>
+<p>The IID of this interface is 00000001-0000-0000-c000-000000000046.</p>
+</div>`
+
+	content := utils.GetAllSection(utils.GetMainContent(bufio.NewReader(strings.NewReader(data))))
+	decl, er := cominterface.HandleInterfacePage("IClassFactory", "/windows/win32/api/unknwn/nn-unknwn-iclassfactory", content)
+	if er != nil {
+		t.Fatal(er)
+	}
+	if decl.BaseInterface != "IUnknown" || decl.IID != "00000001-0000-0000-C000-000000000046" {
+		t.Errorf("Wrong interface: %+v", decl)
+	}
+	if len(decl.Methods) != 2 || decl.Methods[0].Name != "CreateInstance" || decl.Methods[1].Name != "LockServer" {
+		t.Fatalf("Wrong methods: %+v", decl.Methods)
+	}
+	if decl.Methods[0].Path != "/windows/win32/api/unknwn/nf-unknwn-iclassfactory-createinstance" ||
+		decl.Methods[0].Description != "Creates an uninitialized object." ||
+		decl.Methods[0].SymbolName(decl.Name) != "IClassFactory::CreateInstance" {
+		t.Errorf("Wrong method: %+v", decl.Methods[0])
+	}
+
+	parser := tree_sitter.NewParser()
+	defer parser.Close()
+	parser.SetLanguage(tree_sitter.NewLanguage(tree_sitter_c.Language()))
+
+	method, er := cominterface.HandleMethodSyntax(parser, "IClassFactory", `HRESULT CreateInstance(
+  [in]  IUnknown *pUnkOuter,
+  [in]  REFIID   riid,
+  [out] void     **ppvObject
+);`)
+	if er != nil {
+		t.Fatal(er)
+	}
+	if method.Name != "CreateInstance" || method.ReturnType != "HRESULT" || method.Arity != 3 ||
+		method.Parameters[2].Name != "ppvObject" || method.Parameters[2].PointerDepth != 2 {
+		t.Errorf("Wrong method declaration: %+v", method)
+	}
+}
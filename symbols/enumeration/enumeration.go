@@ -0,0 +1,303 @@
+// Contains the function to create EnumDeclaration struct
+package enumeration
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+
+	"github.com/cloakwiss/ntdocs/utils"
+)
+
+type (
+	EnumDeclaration struct {
+		// Tag of the enum i.e. `_ACCESS_MODE` in `typedef enum _ACCESS_MODE {...} ACCESS_MODE;`
+		EnumName string
+		// Names created by the typedef, first one is considered as the name of the enum
+		Names       []string
+		Constants   []EnumConstant
+		Description string
+	}
+
+	EnumConstant struct {
+		Name string
+		// Expression as written in the syntax block, empty when value is computed
+		Expression string
+		Value      int64
+		// False when the value cannot be evaluated from the syntax block
+		Resolved      bool
+		Documentation string
+	}
+)
+
+var (
+	ErrorSomeNewNode       = errors.New("Some new node")
+	ErrorNoEnumFound       = errors.New("No enum found in syntax block")
+	ErrorCannotEvaluate    = errors.New("Cannot evaluate the expression")
+	ErrorConstantsNotFound = errors.New("Cannot find the constants table")
+)
+
+func getString(node *tree_sitter.Node, code []byte) string {
+	return string(code[node.StartByte():node.EndByte()])
+}
+
+// Parse the syntax block of enumeration page, handles both `typedef enum` and bare `enum` declarations
+func HandleSyntaxSection(tree *tree_sitter.Tree, code []byte) (EnumDeclaration, error) {
+	var (
+		rootNode = tree.RootNode()
+		enumDecl EnumDeclaration
+		found    bool
+	)
+
+	for _, node := range rootNode.NamedChildren(rootNode.Walk()) {
+		switch node.Kind() {
+		case "type_definition":
+			specifier := node.ChildByFieldName("type")
+			if specifier == nil || specifier.Kind() != "enum_specifier" {
+				return EnumDeclaration{}, fmt.Errorf("%w : %s", ErrorSomeNewNode, node.Kind())
+			}
+			if er := handleEnum(specifier, &enumDecl, code); er != nil {
+				return EnumDeclaration{}, er
+			}
+			for _, declarator := range node.ChildrenByFieldName("declarator", node.Walk()) {
+				enumDecl.Names = append(enumDecl.Names, getString(&declarator, code))
+			}
+			found = true
+
+		case "enum_specifier":
+			if er := handleEnum(&node, &enumDecl, code); er != nil {
+				return EnumDeclaration{}, er
+			}
+			if enumDecl.EnumName != "" {
+				enumDecl.Names = append(enumDecl.Names, enumDecl.EnumName)
+			}
+			found = true
+
+		case "comment":
+		default:
+			return EnumDeclaration{}, fmt.Errorf("%w : %s", ErrorSomeNewNode, node.Kind())
+		}
+	}
+	if !found || len(enumDecl.Names) == 0 {
+		return EnumDeclaration{}, ErrorNoEnumFound
+	}
+	return enumDecl, nil
+}
+
+func handleEnum(node *tree_sitter.Node, enumDecl *EnumDeclaration, code []byte) error {
+	if name := node.ChildByFieldName("name"); name != nil {
+		enumDecl.EnumName = getString(name, code)
+	}
+	body := node.ChildByFieldName("body")
+	if body == nil {
+		return ErrorNoEnumFound
+	}
+
+	var (
+		// Values of already seen constants, as later constants can refer them
+		known    = make(map[string]int64)
+		next     int64
+		resolved = true
+	)
+	for _, enumerator := range body.NamedChildren(body.Walk()) {
+		switch enumerator.Kind() {
+		case "enumerator":
+			constant := EnumConstant{
+				Name: getString(enumerator.ChildByFieldName("name"), code),
+			}
+			if value := enumerator.ChildByFieldName("value"); value != nil {
+				constant.Expression = getString(value, code)
+				v, er := evaluate(value, code, known)
+				constant.Value, resolved = v, er == nil
+			} else {
+				// Implicit value is only known if the previous one is
+				constant.Value = next
+			}
+			constant.Resolved = resolved
+			if resolved {
+				known[constant.Name] = constant.Value
+				next = constant.Value + 1
+			}
+			enumDecl.Constants = append(enumDecl.Constants, constant)
+
+		case "comment":
+		default:
+			return fmt.Errorf("%w : %s", ErrorSomeNewNode, enumerator.Kind())
+		}
+	}
+	return nil
+}
+
+// Evaluates the constant expression found in the enumerator, only integer arithmetic is supported
+func evaluate(node *tree_sitter.Node, code []byte, known map[string]int64) (int64, error) {
+	switch node.Kind() {
+	case "number_literal":
+		return parseNumber(getString(node, code))
+
+	case "char_literal":
+		text := strings.Trim(getString(node, code), "'")
+		if unquoted, _, _, er := strconv.UnquoteChar(text, '\''); er == nil {
+			return int64(unquoted), nil
+		}
+
+	case "identifier":
+		if v, found := known[getString(node, code)]; found {
+			return v, nil
+		}
+
+	case "parenthesized_expression":
+		if node.NamedChildCount() == 1 {
+			return evaluate(node.NamedChild(0), code, known)
+		}
+
+	case "cast_expression":
+		return evaluate(node.ChildByFieldName("value"), code, known)
+
+	case "unary_expression":
+		arg, er := evaluate(node.ChildByFieldName("argument"), code, known)
+		if er != nil {
+			return 0, er
+		}
+		switch getString(node.ChildByFieldName("operator"), code) {
+		case "-":
+			return -arg, nil
+		case "+":
+			return arg, nil
+		case "~":
+			return ^arg, nil
+		case "!":
+			if arg == 0 {
+				return 1, nil
+			}
+			return 0, nil
+		}
+
+	case "binary_expression":
+		left, er := evaluate(node.ChildByFieldName("left"), code, known)
+		if er != nil {
+			return 0, er
+		}
+		right, er := evaluate(node.ChildByFieldName("right"), code, known)
+		if er != nil {
+			return 0, er
+		}
+		switch getString(node.ChildByFieldName("operator"), code) {
+		case "+":
+			return left + right, nil
+		case "-":
+			return left - right, nil
+		case "*":
+			return left * right, nil
+		case "/":
+			if right != 0 {
+				return left / right, nil
+			}
+		case "%":
+			if right != 0 {
+				return left % right, nil
+			}
+		// Negative count panics in Go
+		case "<<":
+			if right >= 0 {
+				return left << right, nil
+			}
+		case ">>":
+			if right >= 0 {
+				return left >> right, nil
+			}
+		case "|":
+			return left | right, nil
+		case "&":
+			return left & right, nil
+		case "^":
+			return left ^ right, nil
+		}
+	}
+	return 0, fmt.Errorf("%w : %s", ErrorCannotEvaluate, getString(node, code))
+}
+
+func parseNumber(literal string) (int64, error) {
+	if v, ok := utils.ParseCInteger(literal); ok {
+		return v, nil
+	}
+	return 0, fmt.Errorf("%w : %s", ErrorCannotEvaluate, literal)
+}
+
+// Fills documentation of the constants from `Constants` section, the table has name of the constant in bold
+// with optional `Value: ` line and description either in same or the next cell
+func HandleConstantsSection(blocks []*goquery.Selection, enumDecl *EnumDeclaration) error {
+	var table *goquery.Selection
+	for _, blk := range blocks {
+		if blk.Is("table") {
+			table = blk
+			break
+		}
+		if inner := blk.Find("table"); inner.Length() > 0 {
+			table = inner.First()
+			break
+		}
+	}
+	if table == nil {
+		return ErrorConstantsNotFound
+	}
+
+	docs := make(map[string]string, len(enumDecl.Constants))
+	table.Find("tr").Each(func(_ int, row *goquery.Selection) {
+		cells := row.Find("td")
+		if cells.Length() == 0 {
+			return
+		}
+		first := cells.First()
+		name := strings.TrimSpace(first.Find("b, strong").First().Text())
+		if name == "" {
+			return
+		}
+
+		var description string
+		if cells.Length() > 1 {
+			htm, er := cells.Eq(1).Html()
+			if er == nil {
+				description = strings.TrimSpace(htm)
+			}
+		} else {
+			// Everything after the name and value lines is the description
+			clone := first.Clone()
+			clone.Find("b, strong").First().Remove()
+			htm, er := clone.Html()
+			if er == nil {
+				description = strings.TrimSpace(stripValueLine(htm))
+			}
+		}
+		docs[name] = description
+	})
+
+	for i := range enumDecl.Constants {
+		if doc, found := docs[enumDecl.Constants[i].Name]; found {
+			enumDecl.Constants[i].Documentation = doc
+		}
+	}
+	return nil
+}
+
+// Removes the leading `<br/>Value: <i>0</i><br/>` lines from the cell
+func stripValueLine(htm string) string {
+	for {
+		htm = strings.TrimSpace(htm)
+		switch {
+		case strings.HasPrefix(htm, "<br/>"):
+			htm = htm[len("<br/>"):]
+		case strings.HasPrefix(htm, "Value:"):
+			idx := strings.Index(htm, "<br/>")
+			if idx < 0 {
+				return ""
+			}
+			htm = htm[idx:]
+		default:
+			return htm
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package enumeration_test
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+
+	"github.com/k0kubun/pp/v3"
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+	tree_sitter_c "github.com/tree-sitter/tree-sitter-c/bindings/go"
+
+	"github.com/cloakwiss/ntdocs/symbols/enumeration"
+	"github.com/cloakwiss/ntdocs/utils"
+)
+
+func TestEnumeration(t *testing.T) {
+	var data string = `<div class="content"><p>The <b>ACCESS_MODE</b> enumeration contains values that indicate how the access rights in an <a href="/en-us/windows/desktop/api/accctrl/ns-accctrl-explicit_access_a" data-linktype="absolute-path">EXPLICIT_ACCESS</a> structure apply to the trustee.</p>
+<h2 id="syntax">Syntax</h2>
+<pre><code class="lang-cpp">typedef enum _ACCESS_MODE {
+  NOT_USED_ACCESS = 0,
+  GRANT_ACCESS,
+  SET_ACCESS,
+  DENY_ACCESS,
+  REVOKE_ACCESS,
+  SET_AUDIT_SUCCESS,
+  SET_AUDIT_FAILURE = (1 &lt;&lt; 3) | SET_ACCESS,
+  UNKNOWN_ACCESS = SOME_MACRO,
+  AFTER_UNKNOWN
+} ACCESS_MODE, *PACCESS_MODE;
+</code></pre>
+<h2 id="constants">Constants</h2>
+<table>
+<thead>
+<tr>
+<th></th>
+<th></th>
+</tr>
+</thead>
+<tbody>
+<tr>
+<td><b>NOT_USED_ACCESS</b><br/>Value: <i>0</i><br/>Value not used.</td>
+</tr>
+<tr>
+<td><b>GRANT_ACCESS</b></td>
+<td>Indicates an <a href="/en-us/windows/desktop/SecGloss/a-gly" data-linktype="absolute-path">ACCESS_ALLOWED_ACE</a> structure.</td>
+</tr>
+</tbody>
+</table>
+<h2 id="requirements">Requirements</h2>
+<table>
+<tbody>
+<tr>
+<td><strong>Header</strong></td>
+<td>accctrl.h</td>
+</tr>
+</tbody>
+</table>
+</div>`
+
+	backing := strings.NewReader(data)
+	buffer := bufio.NewReader(backing)
+	mainContent := utils.GetMainContent(buffer)
+	content := utils.GetAllSection(mainContent)
+
+	parser := tree_sitter.NewParser()
+	defer parser.Close()
+	parser.SetLanguage(tree_sitter.NewLanguage(tree_sitter_c.Language()))
+
+	code := []byte(content["syntax"][0].Text())
+	tree := parser.Parse(code, nil)
+	defer tree.Close()
+
+	decl, er := enumeration.HandleSyntaxSection(tree, code)
+	if er != nil {
+		t.Fatal(er)
+	}
+	if er := enumeration.HandleConstantsSection(content["constants"], &decl); er != nil {
+		t.Fatal(er)
+	}
+	pp.Println(decl)
+
+	if decl.EnumName != "_ACCESS_MODE" || len(decl.Names) != 2 || decl.Names[0] != "ACCESS_MODE" {
+		t.Fatalf("Wrong names: %s %v", decl.EnumName, decl.Names)
+	}
+	expected := []struct {
+		name     string
+		value    int64
+		resolved bool
+	}{
+		{"NOT_USED_ACCESS", 0, true},
+		{"GRANT_ACCESS", 1, true},
+		{"SET_ACCESS", 2, true},
+		{"DENY_ACCESS", 3, true},
+		{"REVOKE_ACCESS", 4, true},
+		{"SET_AUDIT_SUCCESS", 5, true},
+		{"SET_AUDIT_FAILURE", 10, true},
+		{"UNKNOWN_ACCESS", 0, false},
+		{"AFTER_UNKNOWN", 0, false},
+	}
+	if len(decl.Constants) != len(expected) {
+		t.Fatalf("Expected %d constants found %d", len(expected), len(decl.Constants))
+	}
+	for i, e := range expected {
+		c := decl.Constants[i]
+		if c.Name != e.name || c.Resolved != e.resolved || (e.resolved && c.Value != e.value) {
+			t.Errorf("Constant %d: expected %+v found %+v", i, e, c)
+		}
+	}
+	if decl.Constants[0].Documentation != "Value not used." {
+		t.Errorf("Wrong documentation: %q", decl.Constants[0].Documentation)
+	}
+	if !strings.HasPrefix(decl.Constants[1].Documentation, "Indicates an") {
+		t.Errorf("Wrong documentation: %q", decl.Constants[1].Documentation)
+	}
+}
+
+func TestNegativeShift(t *testing.T) {
+	code := []byte("typedef enum { SHIFTED = 1 << -1, AFTER_SHIFTED, PLAIN = 4 >> 1 } SHIFTS;")
+	parser := tree_sitter.NewParser()
+	defer parser.Close()
+	parser.SetLanguage(tree_sitter.NewLanguage(tree_sitter_c.Language()))
+	tree := parser.Parse(code, nil)
+	defer tree.Close()
+
+	enumDecl, er := enumeration.HandleSyntaxSection(tree, code)
+	if er != nil {
+		t.Fatal(er)
+	}
+	for _, constant := range enumDecl.Constants {
+		switch constant.Name {
+		case "SHIFTED", "AFTER_SHIFTED":
+			if constant.Resolved {
+				t.Errorf("%s is resolved to %d with a negative shift", constant.Name, constant.Value)
+			}
+		case "PLAIN":
+			if !constant.Resolved || constant.Value != 2 {
+				t.Errorf("PLAIN is %d, resolved %v", constant.Value, constant.Resolved)
+			}
+		}
+	}
+}
@@ -3,12 +3,12 @@ package function
 
 import (
 	"errors"
-	"iter"
 	"log"
 	"strings"
 
 	"github.com/PuerkitoBio/goquery"
 	"github.com/k0kubun/pp/v3"
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
 
 	"github.com/cloakwiss/ntdocs/utils"
 )
@@ -19,64 +19,87 @@ import (
 type FunctionDeclarationForInsertion struct {
 	FunctionDeclaration
 	Description, Requirements string
+	ParsedRequirements        utils.Requirements
 	ParameterDescription      utils.AssociativeArray[string, []string]
+	// Keyed by name of the parameter, only parameters documented with `Value | Meaning` table are present
+	ParameterConstants utils.AssociativeArray[string, []utils.ValueDefinition]
+	ReturnValue        ReturnValue
+	// Markdown and plain text forms of Description and ParameterDescription, in the same order
+	RenderedDescription utils.Rendered
+	RenderedParameters  []utils.Rendered
 }
 
 // This type will only data available in Function Page
 type FunctionDeclaration struct {
 	Name, ReturnType string
-	Arity            uint8
-	Parameters       []Parameter
+	// Macro like `WINAPI`, empty when not written
+	CallingConvention string
+	// Import and linkage macros like `NTSYSAPI` or `WINBASEAPI`
+	Specifiers []string
+	Arity      uint8
+	Parameters []Parameter
 }
 
 type Parameter struct {
 	UsageHint, TypeHint, Name string
+	// Number of `*` in declarator, TypeHint already contains them
+	PointerDepth uint8
+	Usage        Usage
 }
 
-// This function does not handle function with no parameter well
-func HandleFunctionDeclarationSectionOfFunction(block []*goquery.Selection) (functionDeclaration FunctionDeclaration) {
-	if len(block) == 1 {
-		seq := strings.SplitSeq(block[0].Text(), "\n")
-		next, stop := iter.Pull(seq)
-		defer stop()
-		{
-
-			if firstLine, n := next(); n {
-				tokens := strings.Split(strings.Trim(firstLine, " \t("), " ")
-				if len(tokens) == 2 {
-					functionDeclaration.ReturnType = tokens[0]
-					functionDeclaration.Name = tokens[1]
-				} else if len(tokens) > 2 {
-					functionDeclaration.ReturnType = strings.Join(tokens[:len(tokens)-1], " ")
-					functionDeclaration.Name = tokens[len(tokens)-1]
-					// pp.Println(returnType, name)
-				} else {
-					st, er := goquery.OuterHtml(block[0])
-					if er != nil {
-						log.Panic("Some other error occured while this....")
-					} else {
-						log.Panicf("Found something strange in first line of function: %s", st)
-					}
+// Parses the syntax block with tree-sitter, usage hints and macros which are not understood by the C grammar
+// are removed before parsing and kept in the declaration
+func HandleFunctionDeclarationSectionOfFunction(parser *tree_sitter.Parser, block []*goquery.Selection) (functionDeclaration FunctionDeclaration, err error) {
+	if len(block) != 1 {
+		err = utils.ErrNotSingleElement
+		return
+	}
+	return HandleFunctionSyntax(parser, block[0].Text())
+}
 
-				}
-			}
+// Parses the first function declaration found in the syntax, also used for macros and COM methods which
+// show their syntax in the same form
+func HandleFunctionSyntax(parser *tree_sitter.Parser, syntax string) (functionDeclaration FunctionDeclaration, err error) {
+	prepared := PrepareSyntax(syntax)
+	code := prepared.Code
+	tree := parser.Parse(code, nil)
+	defer tree.Close()
+	rootNode := tree.RootNode()
+
+	for _, node := range rootNode.NamedChildren(rootNode.Walk()) {
+		if node.Kind() != "declaration" && node.Kind() != "function_definition" {
+			continue
 		}
-		for {
-			if line, found := next(); found {
-				if trimmed := strings.TrimLeft(line, " "); trimmed != "" && trimmed != ");" {
-					if !strings.HasPrefix(trimmed, "\t)") {
-						parameter := splitParameter(trimmed)
-						functionDeclaration.Parameters = append(functionDeclaration.Parameters, parameter)
-						functionDeclaration.Arity += 1
-					}
-				}
-			} else {
-				break
-			}
+		var (
+			declarator = node.ChildByFieldName("declarator")
+			stars      = PointerDepth(declarator)
+		)
+		for declarator != nil && declarator.Kind() == "pointer_declarator" {
+			declarator = declarator.ChildByFieldName("declarator")
+		}
+		if declarator == nil || declarator.Kind() != "function_declarator" {
+			continue
+		}
+
+		// Qualifiers like `const` come before the type
+		returnType := normalizeSpaces(string(code[node.StartByte():node.ChildByFieldName("type").EndByte()]))
+		if stars > 0 {
+			returnType += " " + strings.Repeat("*", int(stars))
+		}
+		functionDeclaration.ReturnType = returnType
+		functionDeclaration.Name = getString(declarator.ChildByFieldName("declarator"), code)
+		functionDeclaration.Parameters = HandleParameterList(declarator.ChildByFieldName("parameters"), code, prepared.UsageHints)
+		functionDeclaration.Arity = uint8(len(functionDeclaration.Parameters))
+		for _, convention := range prepared.CallingConventions {
+			functionDeclaration.CallingConvention = convention.Text
 		}
-	} else {
-		log.Fatal("It have more than one block")
+		for _, specifier := range prepared.Specifiers {
+			functionDeclaration.Specifiers = append(functionDeclaration.Specifiers, specifier.Text)
+		}
+		return
 	}
+
+	err = ErrMissing
 	return
 }
 
@@ -92,33 +115,77 @@ func HandleParameterSectionOfFunction(blocks []*goquery.Selection) (output utils
 		return
 	}
 
-	codeElem := goquery.Single("div.content > p > code:only-child")
-	checkParameterHeader := func(blk *goquery.Selection) (bool, error) {
-		var (
-			code  = blk.FindMatcher(codeElem)
-			found bool
-			err   error
-		)
-		switch code.Length() {
-		case 0:
-			found = false
-		case 1:
-			found = true
-		default:
-			if htm, er := blk.Html(); er == nil {
-				pp.Println(htm)
-			} else {
-				log.Panicln("Comer other error")
+	parameters, err := splitParameterBlocks(blocks)
+	for _, parameter := range parameters {
+		// pp.Println(header.Text())
+		var stringified = make([]string, 0, 4)
+		for _, elem := range parameter.content {
+			text, er := elem.Html()
+			if er != nil {
+				err = er
 			}
-			err = ErrNewCase
+			// pp.Println(text)
+			stringified = append(stringified, text)
+		}
+
+		output = append(output, utils.KV[string, []string]{
+			Key:   strings.Trim(parameter.header.Text(), " "),
+			Value: stringified,
+		})
+	}
+	return
+}
+
+// Same as HandleParameterSectionOfFunction but the documentation is rendered as Markdown and plain text,
+// base is path of the page used for the relative links
+func RenderParameterSection(blocks []*goquery.Selection, base string) (output []utils.Rendered, err error) {
+	if len(blocks) == 0 {
+		return
+	}
+	parameters, err := splitParameterBlocks(blocks)
+	for _, parameter := range parameters {
+		output = append(output, utils.RenderBlocks(parameter.content, base))
+	}
+	return
+}
+
+// Header like `[in] dwFreeType` and the blocks documenting it
+type parameterBlocks struct {
+	header  *goquery.Selection
+	content []*goquery.Selection
+}
+
+var parameterHeader = goquery.Single("div.content > p > code:only-child")
+
+func isParameterHeader(blk *goquery.Selection) (bool, error) {
+	var (
+		code  = blk.FindMatcher(parameterHeader)
+		found bool
+		err   error
+	)
+	switch code.Length() {
+	case 0:
+		found = false
+	case 1:
+		found = true
+	default:
+		if htm, er := blk.Html(); er == nil {
+			pp.Println(htm)
+		} else {
+			log.Panicln("Comer other error")
 		}
-		return found, err
+		err = ErrNewCase
 	}
+	return found, err
+}
 
+// Splits the blocks of parameters section at each parameter header,
+// same layout is used by members section of structures
+func splitParameterBlocks(blocks []*goquery.Selection) (output []parameterBlocks, err error) {
 	var markings = make([]int, 0)
 
 	for i, blk := range blocks {
-		found, er := checkParameterHeader(blk)
+		found, er := isParameterHeader(blk)
 		if er == nil {
 			if found {
 				markings = append(markings, i)
@@ -142,62 +209,78 @@ func HandleParameterSectionOfFunction(blocks []*goquery.Selection) (output utils
 		}
 	}
 
-	if len(markers) > 0 {
-		for _, marker := range markers {
-			// pp.Println(marker)
-			header := blocks[marker[0]]
-			if marker[0]+1 <= marker[1] && marker[1] <= len(blocks) {
-				content := blocks[marker[0]+1 : marker[1]]
-
-				// pp.Println(header.Text())
-				var stringified = make([]string, 0, 4)
-				for _, elem := range content {
-					text, er := elem.Html()
-					if er != nil {
-						err = er
-					}
-					// pp.Println(text)
-					stringified = append(stringified, text)
-				}
-
-				output = append(output, utils.KV[string, []string]{
-					Key:   strings.Trim(header.Text(), " "),
-					Value: stringified,
-				})
-			} else {
-				err = ErrRangingProblem
-			}
+	for _, marker := range markers {
+		// pp.Println(marker)
+		if marker[0]+1 <= marker[1] && marker[1] <= len(blocks) {
+			output = append(output, parameterBlocks{header: blocks[marker[0]], content: blocks[marker[0]+1 : marker[1]]})
+		} else {
+			err = ErrRangingProblem
 		}
 	}
 	return
 }
 
-func splitParameter(line string) Parameter {
-	var idx, l int = 0, len(line)
-
-	// usage hint
-	for ; idx < l && line[idx] != '['; idx += 1 {
+// Extracts the `Value | Meaning` tables documenting the flags and constants accepted by each parameter,
+// keyed by the name of parameter. Also works with members section of structures.
+// Parameters without any table are not included.
+func HandleParameterConstants(blocks []*goquery.Selection) (output utils.AssociativeArray[string, []utils.ValueDefinition], err error) {
+	if blocks, err = skipIntroduction(blocks); err != nil || len(blocks) == 0 {
+		return
 	}
-	for ; idx < l && line[idx] != ']'; idx += 1 {
+
+	parameters, err := splitParameterBlocks(blocks)
+	for _, parameter := range parameters {
+		var definitions []utils.ValueDefinition
+		for _, elem := range parameter.content {
+			// Block is the table itself most of the time, but it can also be wrapped
+			for _, table := range elem.Filter("table").AddSelection(elem.Find("table")).EachIter() {
+				if found, rows := utils.HandleValueTable(table); found {
+					definitions = append(definitions, rows...)
+				}
+			}
+		}
+		fields := strings.Fields(parameter.header.Text())
+		if len(definitions) == 0 || len(fields) == 0 {
+			continue
+		}
+		output = append(output, utils.KV[string, []utils.ValueDefinition]{
+			Key:   fields[len(fields)-1],
+			Value: definitions,
+		})
 	}
-	idx += 1
+	return
+}
 
-	if idx >= l {
-		sig := strings.SplitAfter(line, " ")
+// Documentation of each member in members section of structures, keyed by the name of member in the order of the page.
+// Base is path of the page used for the relative links.
+func HandleMemberSection(blocks []*goquery.Selection, base string) (output utils.AssociativeArray[string, utils.Documentation], err error) {
+	if blocks, err = skipIntroduction(blocks); err != nil || len(blocks) == 0 {
+		return
+	}
 
-		return Parameter{
-			UsageHint: "",
-			TypeHint:  strings.Trim(strings.Join(sig[:len(sig)-1], " "), " "),
-			Name:      strings.TrimRight(sig[len(sig)-1], ", "),
+	members, err := splitParameterBlocks(blocks)
+	for _, member := range members {
+		fields := strings.Fields(member.header.Text())
+		if len(fields) == 0 {
+			continue
 		}
-	} else {
-		sig := strings.SplitAfter(line[idx:], " ")
+		output = append(output, utils.KV[string, utils.Documentation]{
+			Key:   fields[len(fields)-1],
+			Value: utils.Document(member.content, base),
+		})
+	}
+	return
+}
 
-		return Parameter{
-			UsageHint: strings.Trim(line[:idx], "[]"),
-			TypeHint:  strings.Trim(strings.Join(sig[:len(sig)-1], " "), " "),
-			Name:      strings.TrimRight(sig[len(sig)-1], ", "),
+// Members section can have some introduction before the first member
+func skipIntroduction(blocks []*goquery.Selection) ([]*goquery.Selection, error) {
+	for len(blocks) > 0 {
+		if found, er := isParameterHeader(blocks[0]); er != nil {
+			return nil, er
+		} else if found {
+			break
 		}
+		blocks = blocks[1:]
 	}
-
+	return blocks, nil
 }
@@ -5,8 +5,14 @@ import (
 	"strings"
 	"testing"
 
-	"github.com/cloakwiss/ntdocs/utils"
+	"github.com/PuerkitoBio/goquery"
 	"github.com/k0kubun/pp/v3"
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+	tree_sitter_c "github.com/tree-sitter/tree-sitter-c/bindings/go"
+	"golang.org/x/net/html"
+
+	"github.com/cloakwiss/ntdocs/symbols/function"
+	"github.com/cloakwiss/ntdocs/utils"
 )
 
 func TestParameters(t *testing.T) {
@@ -162,3 +168,265 @@ Frees an allocation back to a placeholder (after you've replaced a placeholder w
 		pp.Println(requirements)
 	}
 }
+
+func TestFunctionDeclaration(t *testing.T) {
+	parser := tree_sitter.NewParser()
+	defer parser.Close()
+	parser.SetLanguage(tree_sitter.NewLanguage(tree_sitter_c.Language()))
+
+	cases := []struct {
+		syntax, name, returnType, convention string
+		parameters                           []function.Parameter
+	}{
+		{
+			syntax: `BOOL GetModuleHandleExW(
+  [in]           DWORD   dwFlags,
+  [in, optional] LPCWSTR lpModuleName,
+  [out]          HMODULE *phModule
+);`,
+			name: "GetModuleHandleExW", returnType: "BOOL",
+			parameters: []function.Parameter{
+				{UsageHint: "in", TypeHint: "DWORD", Name: "dwFlags", Usage: function.Usage{Direction: function.DirectionIn}},
+				{UsageHint: "in, optional", TypeHint: "LPCWSTR", Name: "lpModuleName", Usage: function.Usage{Direction: function.DirectionIn, Optional: true}},
+				{UsageHint: "out", TypeHint: "HMODULE *", Name: "phModule", PointerDepth: 1, Usage: function.Usage{Direction: function.DirectionOut}},
+			},
+		},
+		{
+			syntax: `NTSYSAPI VOID RtlCaptureContext(
+  [out] PCONTEXT ContextRecord
+);`,
+			name: "RtlCaptureContext", returnType: "VOID",
+			parameters: []function.Parameter{
+				{UsageHint: "out", TypeHint: "PCONTEXT", Name: "ContextRecord", Usage: function.Usage{Direction: function.DirectionOut}},
+			},
+		},
+		{
+			syntax:     `DWORD GetCurrentProcessorNumber();`,
+			name:       "GetCurrentProcessorNumber",
+			returnType: "DWORD",
+		},
+		{
+			syntax:     `HANDLE WINAPI GetCurrentProcess(void);`,
+			name:       "GetCurrentProcess",
+			returnType: "HANDLE", convention: "WINAPI",
+		},
+		{
+			syntax: `_Must_inspect_result_ const char ** __stdcall Foo(
+  _In_reads_bytes_opt_(nSize) const void *const *lpBuffer,
+  ...
+);`,
+			name: "Foo", returnType: "const char **", convention: "__stdcall",
+			parameters: []function.Parameter{
+				{UsageHint: "_In_reads_bytes_opt_(nSize)", TypeHint: "const void *const *", Name: "lpBuffer", PointerDepth: 2,
+					Usage: function.Usage{Direction: function.DirectionIn, Optional: true, SizeParameter: "nSize", SizeUnit: "bytes"}},
+				{TypeHint: "..."},
+			},
+		},
+		{
+			syntax:     `STDAPI_(BOOL) PathIsUNCW(LPCWSTR pszPath);`,
+			name:       "PathIsUNCW",
+			returnType: "BOOL", convention: "STDAPICALLTYPE",
+			parameters: []function.Parameter{
+				{TypeHint: "LPCWSTR", Name: "pszPath"},
+			},
+		},
+	}
+
+	for _, c := range cases {
+		block := []*goquery.Selection{goquery.NewDocumentFromNode(&html.Node{
+			Type: html.TextNode, Data: c.syntax,
+		}).Selection}
+		sig, er := function.HandleFunctionDeclarationSectionOfFunction(parser, block)
+		if er != nil {
+			t.Fatalf("%s: %v", c.name, er)
+		}
+		pp.Println(sig)
+		if sig.Name != c.name || sig.ReturnType != c.returnType || sig.CallingConvention != c.convention {
+			t.Errorf("Wrong signature: %+v", sig)
+		}
+		if int(sig.Arity) != len(c.parameters) {
+			t.Fatalf("%s: expected %d parameters found %d", c.name, len(c.parameters), sig.Arity)
+		}
+		for i := range c.parameters {
+			if sig.Parameters[i] != c.parameters[i] {
+				t.Errorf("%s parameter %d: expected %+v found %+v", c.name, i, c.parameters[i], sig.Parameters[i])
+			}
+		}
+	}
+}
+
+func TestUsageHint(t *testing.T) {
+	cases := []struct {
+		hint  string
+		usage function.Usage
+	}{
+		{"in, out", function.Usage{Direction: function.DirectionInOut}},
+		{"out, optional", function.Usage{Direction: function.DirectionOut, Optional: true}},
+		{"in", function.Usage{Direction: function.DirectionIn}},
+		{"reserved", function.Usage{Reserved: true}},
+		{"_Reserved_", function.Usage{Direction: function.DirectionIn, Reserved: true}},
+		{"_Inout_opt_", function.Usage{Direction: function.DirectionInOut, Optional: true}},
+		{"_Out_writes_to_(nSize, *pcbWritten)", function.Usage{Direction: function.DirectionOut, SizeParameter: "nSize", SizeUnit: "elements"}},
+	}
+	for _, c := range cases {
+		if usage := function.ParseUsageHint(c.hint); usage != c.usage {
+			t.Errorf("%s: expected %+v found %+v", c.hint, c.usage, usage)
+		}
+	}
+
+	// Array sizes written as macros are not hints
+	code := []byte("BOOL Foo(\n  [out] WCHAR name[MAX_PATH],\n  [in] DWORD counts[ANYSIZE_ARRAY], [in, optional] LPVOID p\n);")
+	annotations := function.BlankUsageHints(code)
+	if len(annotations) != 3 || annotations[0].Text != "out" || annotations[1].Text != "in" || annotations[2].Text != "in, optional" {
+		t.Errorf("Wrong hints: %+v", annotations)
+	}
+	if !strings.Contains(string(code), "name[MAX_PATH]") || !strings.Contains(string(code), "counts[ANYSIZE_ARRAY]") {
+		t.Errorf("Array size is blanked: %s", code)
+	}
+
+	parameters := []function.Parameter{
+		{Name: "lpBuffer", Usage: function.Usage{Direction: function.DirectionOut}},
+		{Name: "nSize", Usage: function.Usage{Direction: function.DirectionIn}},
+	}
+	descriptions := utils.AssociativeArray[string, []string]{
+		{Key: "[out] lpBuffer", Value: []string{"A pointer to a buffer that receives the data."}},
+		{Key: "[in] nSize", Value: []string{"The size of the <i>lpBuffer</i> buffer, in bytes."}},
+	}
+	function.LinkSizeParameters(parameters, descriptions)
+	if parameters[0].Usage.SizeParameter != "nSize" || parameters[0].Usage.SizeUnit != "bytes" {
+		t.Errorf("Size relation not found: %+v", parameters[0].Usage)
+	}
+}
+
+func TestReturnValue(t *testing.T) {
+	cases := []struct {
+		htm, returnType string
+		expected        function.ReturnValue
+	}{
+		{
+			`<p>If the function succeeds, the return value is nonzero.</p>
+<p>If the function fails, the return value is 0 (zero). To get extended error information, call <a href="/en-us/windows/win32/api/errhandlingapi/nf-errhandlingapi-getlasterror" data-linktype="absolute-path">GetLastError</a>.</p>`,
+			"BOOL",
+			function.ReturnValue{Convention: function.ConventionNonzero, SuccessValue: "nonzero", FailureValue: "0", LastError: true},
+		},
+		{
+			`<p>If the function succeeds, the return value is an open handle to the specified file.</p>
+<p>If the function fails, the return value is <b>INVALID_HANDLE_VALUE</b>. To get extended error information, call <b>GetLastError</b>.</p>`,
+			"HANDLE",
+			function.ReturnValue{Convention: function.ConventionInvalidHandle, FailureValue: "INVALID_HANDLE_VALUE", LastError: true},
+		},
+		{
+			`<p>If the function fails, the return value is NULL.</p>`,
+			"HMODULE",
+			function.ReturnValue{Convention: function.ConventionNull, FailureValue: "NULL"},
+		},
+		{
+			`<p>If this function succeeds, it returns <b>S_OK</b>. Otherwise, it returns an <b>HRESULT</b> error code.</p>`,
+			"HRESULT",
+			function.ReturnValue{Convention: function.ConventionHResult, SuccessValue: "S_OK"},
+		},
+		{
+			`<p>If the function succeeds, the return value is ERROR_SUCCESS.</p><p>If the function fails, the return value is a system error code.</p>`,
+			"LSTATUS",
+			function.ReturnValue{Convention: function.ConventionWin32Error, SuccessValue: "ERROR_SUCCESS"},
+		},
+		{`<p>None</p>`, "void", function.ReturnValue{Convention: function.ConventionNone}},
+	}
+
+	for _, c := range cases {
+		doc, er := goquery.NewDocumentFromReader(strings.NewReader(c.htm))
+		if er != nil {
+			t.Fatal(er)
+		}
+		var blocks []*goquery.Selection
+		for _, p := range doc.Find("p").EachIter() {
+			blocks = append(blocks, p)
+		}
+		returnValue := function.HandleReturnValueSectionOfFunction(blocks, c.returnType)
+		returnValue.Documentation = ""
+		if returnValue != c.expected {
+			t.Errorf("%s: expected %+v found %+v", c.returnType, c.expected, returnValue)
+		}
+	}
+}
+
+func TestParameterConstants(t *testing.T) {
+	htm := `<div class="content"><h2 id="parameters">Parameters</h2>
+<p><code>[in] lpAddress</code></p>
+<p>A pointer to the base address of the region of pages to be freed.</p>
+<p><code>[in] dwFreeType</code></p>
+<p>The type of free operation. This parameter must be one of the following values.</p>
+<table>
+<thead><tr><th>Value</th><th>Meaning</th></tr></thead>
+<tbody>
+<tr><td width="40%"><a id="MEM_DECOMMIT"></a><a id="mem_decommit"></a><dl>
+<dt><b>MEM_DECOMMIT</b></dt>
+<dt>0x00004000</dt>
+</dl></td><td width="60%">Decommits the specified region of committed pages.</td></tr>
+<tr><td width="40%"><dl>
+<dt><b>MEM_RELEASE</b></dt>
+<dt>0x00008000</dt>
+</dl></td><td width="60%">Releases the specified region of pages.</td></tr>
+<tr><td width="40%"><dl>
+<dt><b>MEM_PRESERVE_PLACEHOLDER</b></dt>
+</dl></td><td width="60%">Frees an allocation back to a placeholder.</td></tr>
+</tbody>
+</table>
+</div>`
+	doc, er := goquery.NewDocumentFromReader(strings.NewReader(htm))
+	if er != nil {
+		t.Fatal(er)
+	}
+	content := utils.GetAllSection(doc.Find("div.content").First())
+
+	constants, er := function.HandleParameterConstants(content["parameters"])
+	if er != nil {
+		t.Fatal(er)
+	}
+	if len(constants) != 1 || constants[0].Key != "dwFreeType" {
+		t.Fatalf("Expected constants only for dwFreeType, found: %+v", constants)
+	}
+	expected := []utils.ValueDefinition{
+		{Name: "MEM_DECOMMIT", ValueText: "0x00004000", Value: 0x4000, Resolved: true, Description: "Decommits the specified region of committed pages."},
+		{Name: "MEM_RELEASE", ValueText: "0x00008000", Value: 0x8000, Resolved: true, Description: "Releases the specified region of pages."},
+		{Name: "MEM_PRESERVE_PLACEHOLDER", Description: "Frees an allocation back to a placeholder."},
+	}
+	if len(constants[0].Value) != len(expected) {
+		t.Fatalf("Expected %d constants, found: %+v", len(expected), constants[0].Value)
+	}
+	for i := range expected {
+		if constants[0].Value[i] != expected[i] {
+			t.Errorf("Expected: %+v, found: %+v", expected[i], constants[0].Value[i])
+		}
+	}
+}
+
+func TestMemberSection(t *testing.T) {
+	htm := `<div class="content"><h2 id="members">Members</h2>
+<p>The structure has the following members.</p>
+<p><code>cEntries</code></p>
+<p>The number of entries in the <b>pAccessList</b> array.</p>
+<p><code>pAccessList</code></p>
+<p>A pointer to an array of <a href="ns-accctrl-actrl_access_entrya" data-linktype="relative-path">ACTRL_ACCESS_ENTRY</a> structures.</p>
+</div>`
+	doc, er := goquery.NewDocumentFromReader(strings.NewReader(htm))
+	if er != nil {
+		t.Fatal(er)
+	}
+	content := utils.GetAllSection(doc.Find("div.content").First())
+
+	members, er := function.HandleMemberSection(content["members"], "/windows/win32/api/accctrl/ns-accctrl-actrl_access_entry_lista")
+	if er != nil {
+		t.Fatal(er)
+	}
+	if len(members) != 2 || members[0].Key != "cEntries" || members[1].Key != "pAccessList" {
+		t.Fatalf("Wrong members: %+v", members)
+	}
+	if members[0].Value.Html != "The number of entries in the <b>pAccessList</b> array." ||
+		members[0].Value.Text != "The number of entries in the pAccessList array." {
+		t.Errorf("Wrong documentation: %+v", members[0].Value)
+	}
+	if expected := "A pointer to an array of [ACTRL_ACCESS_ENTRY](https://learn.microsoft.com/en-us/windows/win32/api/accctrl/ns-accctrl-actrl_access_entrya) structures."; members[1].Value.Markdown != expected {
+		t.Errorf("Expected: %s, found: %s", expected, members[1].Value.Markdown)
+	}
+}
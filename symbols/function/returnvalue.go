@@ -0,0 +1,98 @@
+// Contains the function to classify the Return value section of function
+package function
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+
+	"github.com/cloakwiss/ntdocs/utils"
+)
+
+// How the failure of function is reported to the caller
+type ErrorConvention string
+
+const (
+	ConventionUnknown ErrorConvention = "unknown"
+	// Function does not return anything
+	ConventionNone ErrorConvention = "none"
+	// Nonzero on success and zero (FALSE) on failure, like most BOOL functions
+	ConventionNonzero ErrorConvention = "nonzero"
+	// NULL on failure, like functions returning handles or pointers
+	ConventionNull ErrorConvention = "null"
+	// INVALID_HANDLE_VALUE on failure
+	ConventionInvalidHandle ErrorConvention = "invalid-handle"
+	// S_OK or other HRESULT
+	ConventionHResult ErrorConvention = "hresult"
+	// STATUS_SUCCESS or other NTSTATUS
+	ConventionNTStatus ErrorConvention = "ntstatus"
+	// ERROR_SUCCESS or system error code, like registry functions
+	ConventionWin32Error ErrorConvention = "win32-error"
+)
+
+type ReturnValue struct {
+	// Inner html of the section
+	Documentation string
+	Convention    ErrorConvention
+	// Values as written in the documentation i.e. "NULL", "INVALID_HANDLE_VALUE" or "0"
+	SuccessValue, FailureValue string
+	// True when documentation asks to call GetLastError for extended error
+	LastError bool
+}
+
+var (
+	// Only the constants, numbers and zero/nonzero are captured, not the prose like "an open handle"
+	failurePattern = regexp.MustCompile(`(?i:\bif the function fails,?\s+(?:the\s+)?return value is\s+(?:an?\s+)?)([A-Z][A-Z0-9_]+|\d+|nonzero|zero)\b`)
+	successPattern = regexp.MustCompile(`(?i:\bif the function succeeds,?\s+(?:the\s+)?return value is\s+(?:an?\s+)?)([A-Z][A-Z0-9_]+|\d+|nonzero|zero)\b`)
+)
+
+func HandleReturnValueSectionOfFunction(blocks []*goquery.Selection, returnType string) (returnValue ReturnValue) {
+	returnValue.Documentation = utils.JoinBlocks(blocks)
+
+	var texts = make([]string, 0, len(blocks))
+	for _, block := range blocks {
+		texts = append(texts, block.Text())
+	}
+	text := strings.Join(strings.Fields(strings.Join(texts, " ")), " ")
+	lower := strings.ToLower(text)
+
+	returnValue.LastError = strings.Contains(lower, "getlasterror")
+	if match := successPattern.FindStringSubmatch(text); match != nil {
+		returnValue.SuccessValue = match[1]
+	}
+	if match := failurePattern.FindStringSubmatch(text); match != nil {
+		returnValue.FailureValue = match[1]
+	}
+	failure := strings.ToUpper(returnValue.FailureValue)
+	returnType = strings.ToUpper(strings.TrimSpace(returnType))
+
+	switch {
+	case returnType == "VOID" || strings.Contains(lower, "does not return a value") || lower == "none" || lower == "none.":
+		returnValue.Convention = ConventionNone
+	case failure == "INVALID_HANDLE_VALUE":
+		returnValue.Convention = ConventionInvalidHandle
+	case failure == "NULL":
+		returnValue.Convention = ConventionNull
+	case failure == "ZERO" || failure == "0" || failure == "FALSE":
+		returnValue.Convention = ConventionNonzero
+		returnValue.FailureValue = "0"
+		if returnValue.SuccessValue == "" {
+			returnValue.SuccessValue = "nonzero"
+		}
+	case returnType == "HRESULT" || strings.Contains(lower, "s_ok"):
+		returnValue.Convention = ConventionHResult
+		returnValue.SuccessValue = "S_OK"
+	case returnType == "NTSTATUS" || strings.Contains(lower, "status_success"):
+		returnValue.Convention = ConventionNTStatus
+		returnValue.SuccessValue = "STATUS_SUCCESS"
+	case strings.Contains(lower, "error_success"):
+		returnValue.Convention = ConventionWin32Error
+		returnValue.SuccessValue = "ERROR_SUCCESS"
+	case strings.Contains(lower, "return value is nonzero"):
+		returnValue.Convention = ConventionNonzero
+	default:
+		returnValue.Convention = ConventionUnknown
+	}
+	return
+}
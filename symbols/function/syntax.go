@@ -0,0 +1,190 @@
+// Contains helpers to prepare the syntax block for tree-sitter and read the parameters back from the tree
+package function
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// Part of the syntax block which was removed before parsing, as the C grammar does not know about it.
+// It is overwritten by spaces so byte offsets of the tree still point into the original code.
+type Annotation struct {
+	Start, End uint
+	Text       string
+}
+
+// Macros which only decide the calling convention of the function
+var CallingConventions = []string{
+	"WINAPI", "WINAPIV", "APIENTRY", "CALLBACK", "NTAPI", "PASCAL", "FASTCALL",
+	"STDMETHODCALLTYPE", "STDMETHODVCALLTYPE", "STDAPICALLTYPE", "STDAPIVCALLTYPE",
+	"__stdcall", "__cdecl", "__fastcall", "__vectorcall", "__clrcall", "_stdcall", "_cdecl",
+}
+
+var (
+	// Hint is before the type, at the start of line or after `(` and `,`. Array size like `name[MAX_PATH]` is after
+	// the name, so it is not matched
+	usageHintPattern = regexp.MustCompile(`(?m)(?:^|[(,])\s*(\[[a-zA-Z_, ]*\])`)
+	// SAL annotations like `_In_`, `_Out_writes_bytes_(nSize)` or `_Must_inspect_result_`
+	salPattern = regexp.MustCompile(`\b_(?:In|Out|Inout|Outptr|Reserved|Ret|Must|Success|Frees|Check|Printf|Deref|Pre|Post|When|Null|NullNull|Notnull|Maybenull|Field|Struct|Kernel|IRQL|Function|Use|Always|Interlocked|Result|Acquires|Releases|Requires)\w*_(?:\((?:[^()]|\([^()]*\))*\))?`)
+	// Import and linkage macros like `NTSYSAPI`, `WINBASEAPI` or `__declspec(dllimport)`
+	specifierPattern = regexp.MustCompile(`\b(?:[A-Z][A-Z0-9_]*API|DECLSPEC_[A-Z_]+|EXTERN_C|extern|FORCEINLINE|__inline|inline)\b|__declspec\([^)]*\)`)
+	// `STDAPI` and its friends also declare the return type, `STDAPI_(BOOL)` returns BOOL and `STDAPI` returns HRESULT
+	stdapiPattern = regexp.MustCompile(`\b(?:STDAPI|SHSTDAPI|WINOLEAPI|SHDOCAPI|LWSTDAPI|STDAPIV)(?:_\(([^)]*)\))?`)
+)
+
+// Syntax block with all the annotations, which are not understood by C grammar, replaced by spaces
+type PreparedSyntax struct {
+	Code                                       []byte
+	UsageHints, CallingConventions, Specifiers []Annotation
+}
+
+func PrepareSyntax(syntax string) PreparedSyntax {
+	syntax = stdapiPattern.ReplaceAllStringFunc(syntax, func(macro string) string {
+		if match := stdapiPattern.FindStringSubmatch(macro); match[1] != "" {
+			return match[1] + " STDAPICALLTYPE"
+		}
+		return "HRESULT STDAPICALLTYPE"
+	})
+
+	prepared := PreparedSyntax{Code: []byte(syntax)}
+	prepared.UsageHints = BlankUsageHints(prepared.Code)
+	prepared.UsageHints = append(prepared.UsageHints, blankPattern(prepared.Code, salPattern)...)
+	slices.SortFunc(prepared.UsageHints, compareAnnotation)
+	prepared.CallingConventions = BlankMacros(prepared.Code, CallingConventions)
+	prepared.Specifiers = blankPattern(prepared.Code, specifierPattern)
+	return prepared
+}
+
+// Replaces `[in, optional]` style hints with spaces, the text inside brackets is returned in order
+func BlankUsageHints(code []byte) []Annotation {
+	var annotations []Annotation
+	for _, loc := range usageHintPattern.FindAllSubmatchIndex(code, -1) {
+		loc = loc[2:]
+		annotations = append(annotations, Annotation{
+			Start: uint(loc[0]),
+			End:   uint(loc[1]),
+			Text:  strings.TrimSpace(string(code[loc[0]+1 : loc[1]-1])),
+		})
+		blank(code[loc[0]:loc[1]])
+	}
+	return annotations
+}
+
+// Replaces every whole word occurrence of the macros with spaces
+func BlankMacros(code []byte, macros []string) []Annotation {
+	var annotations []Annotation
+	for _, macro := range macros {
+		pattern := regexp.MustCompile(`\b` + regexp.QuoteMeta(macro) + `\b`)
+		annotations = append(annotations, blankPattern(code, pattern)...)
+	}
+	slices.SortFunc(annotations, compareAnnotation)
+	return annotations
+}
+
+func compareAnnotation(a, b Annotation) int {
+	return int(a.Start) - int(b.Start)
+}
+
+func blankPattern(code []byte, pattern *regexp.Regexp) []Annotation {
+	var annotations []Annotation
+	for _, loc := range pattern.FindAllIndex(code, -1) {
+		annotations = append(annotations, Annotation{
+			Start: uint(loc[0]),
+			End:   uint(loc[1]),
+			Text:  string(code[loc[0]:loc[1]]),
+		})
+		blank(code[loc[0]:loc[1]])
+	}
+	return annotations
+}
+
+func blank(code []byte) {
+	for i := range code {
+		code[i] = ' '
+	}
+}
+
+func getString(node *tree_sitter.Node, code []byte) string {
+	return string(code[node.StartByte():node.EndByte()])
+}
+
+// Returns the identifier inside of the declarator, nil for abstract declarators
+func DeclaratorName(node *tree_sitter.Node) *tree_sitter.Node {
+	for node != nil {
+		switch node.Kind() {
+		case "identifier", "type_identifier", "field_identifier":
+			return node
+		case "parenthesized_declarator":
+			node = node.NamedChild(node.NamedChildCount() - 1)
+		default:
+			node = node.ChildByFieldName("declarator")
+		}
+	}
+	return nil
+}
+
+// Number of pointer declarators wrapping the identifier, `HMODULE *phModule` has depth of 1
+func PointerDepth(node *tree_sitter.Node) uint8 {
+	var depth uint8
+	for node != nil {
+		switch node.Kind() {
+		case "pointer_declarator", "abstract_pointer_declarator":
+			depth += 1
+			node = node.ChildByFieldName("declarator")
+		case "array_declarator", "abstract_array_declarator":
+			node = node.ChildByFieldName("declarator")
+		default:
+			return depth
+		}
+	}
+	return depth
+}
+
+// Reads the parameter list of the declarator, usage hints are matched with the parameters by position.
+// `(void)` is treated as no parameter.
+func HandleParameterList(list *tree_sitter.Node, code []byte, hints []Annotation) []Parameter {
+	var (
+		parameters []Parameter
+		previous   = list.StartByte()
+	)
+	for _, param := range list.NamedChildren(list.Walk()) {
+		var parameter Parameter
+		switch param.Kind() {
+		case "parameter_declaration":
+			parameter.PointerDepth = PointerDepth(param.ChildByFieldName("declarator"))
+			name := DeclaratorName(param.ChildByFieldName("declarator"))
+			if name == nil {
+				parameter.TypeHint = normalizeSpaces(getString(&param, code))
+			} else {
+				parameter.Name = getString(name, code)
+				parameter.TypeHint = normalizeSpaces(string(code[param.StartByte():name.StartByte()]) +
+					string(code[name.EndByte():param.EndByte()]))
+			}
+		case "variadic_parameter":
+			parameter.TypeHint = "..."
+		default:
+			continue
+		}
+		var found []string
+		for _, hint := range hints {
+			if previous <= hint.Start && hint.End <= param.StartByte() {
+				found = append(found, hint.Text)
+			}
+		}
+		parameter.UsageHint = strings.Join(found, " ")
+		parameter.Usage = ParseUsageHint(parameter.UsageHint)
+		previous = param.EndByte()
+		parameters = append(parameters, parameter)
+	}
+	if len(parameters) == 1 && parameters[0].Name == "" && parameters[0].TypeHint == "void" {
+		return nil
+	}
+	return parameters
+}
+
+func normalizeSpaces(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
@@ -0,0 +1,147 @@
+// Contains the typed model of the usage hint written before the parameters
+package function
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/cloakwiss/ntdocs/utils"
+)
+
+type Direction uint8
+
+const (
+	DirectionUnknown Direction = iota
+	DirectionIn
+	DirectionOut
+	DirectionInOut
+)
+
+func (d Direction) String() string {
+	switch d {
+	case DirectionIn:
+		return "in"
+	case DirectionOut:
+		return "out"
+	case DirectionInOut:
+		return "inout"
+	default:
+		return ""
+	}
+}
+
+// Parsed form of `[in, optional]` or SAL annotations like `_Out_writes_bytes_opt_(nSize)`
+type Usage struct {
+	Direction          Direction
+	Optional, Reserved bool
+	// Expression giving the size of the buffer, mostly the name of other parameter
+	SizeParameter string
+	// "bytes" or "elements", empty when size is not known
+	SizeUnit string
+}
+
+var (
+	salAnnotationPattern = regexp.MustCompile(`^_([A-Za-z_]+?)_(?:\((.*)\))?$`)
+	// First sentence of documentation like `The size of the buffer pointed to by <i>lpBuffer</i>, in bytes.`
+	sizeDocumentationPattern = regexp.MustCompile(`(?i)^\s*(?:<p>)?\s*(?:the|a)?\s*(?:maximum\s+)?(size|length|number of (?:bytes|characters|elements|wchars|tchars))\b[^.]*?<(?:i|em)>(\w+)</(?:i|em)>([^.]*)`)
+)
+
+// Hint can contain both the bracket form and SAL annotations as collected by HandleParameterList
+func ParseUsageHint(hint string) (usage Usage) {
+	for _, annotation := range salPattern.FindAllString(hint, -1) {
+		parseSALAnnotation(annotation, &usage)
+	}
+	hint = salPattern.ReplaceAllString(hint, " ")
+	for _, token := range strings.Fields(strings.ReplaceAll(hint, ",", " ")) {
+		switch strings.ToLower(token) {
+		case "in":
+			usage.Direction = merge(usage.Direction, DirectionIn)
+		case "out", "retval":
+			usage.Direction = merge(usage.Direction, DirectionOut)
+		case "inout", "in/out":
+			usage.Direction = DirectionInOut
+		case "optional", "opt":
+			usage.Optional = true
+		case "reserved":
+			usage.Reserved = true
+		}
+	}
+	return
+}
+
+func merge(current, next Direction) Direction {
+	if current == DirectionUnknown || current == next {
+		return next
+	}
+	return DirectionInOut
+}
+
+func parseSALAnnotation(annotation string, usage *Usage) {
+	match := salAnnotationPattern.FindStringSubmatch(annotation)
+	if match == nil {
+		return
+	}
+	var (
+		parts    = strings.Split(strings.ToLower(match[1]), "_")
+		argument = match[2]
+	)
+	switch parts[0] {
+	case "in", "frees":
+		usage.Direction = merge(usage.Direction, DirectionIn)
+	case "out", "outptr", "deref":
+		usage.Direction = merge(usage.Direction, DirectionOut)
+	case "inout":
+		usage.Direction = DirectionInOut
+	case "reserved":
+		usage.Direction = merge(usage.Direction, DirectionIn)
+		usage.Reserved = true
+	}
+
+	var sized bool
+	for _, part := range parts[1:] {
+		switch part {
+		case "opt":
+			usage.Optional = true
+		case "reads", "writes", "updates":
+			sized = true
+			if usage.SizeUnit == "" {
+				usage.SizeUnit = "elements"
+			}
+		case "bytes":
+			usage.SizeUnit = "bytes"
+		}
+	}
+	if sized && argument != "" {
+		// `_Out_writes_to_(size, count)` has the capacity as the first argument
+		size, _, _ := strings.Cut(argument, ",")
+		usage.SizeParameter = strings.TrimSpace(size)
+	}
+}
+
+// Documentation of a parameter holding the size of other parameter usually starts with `The size of ... <i>lpBuffer</i>`,
+// this relation is added to usage of buffer parameter when not already known from annotation
+func LinkSizeParameters(parameters []Parameter, descriptions utils.AssociativeArray[string, []string]) {
+	index := make(map[string]int, len(parameters))
+	for i, parameter := range parameters {
+		index[parameter.Name] = i
+	}
+	for i, description := range descriptions {
+		if i >= len(parameters) || len(description.Value) == 0 {
+			continue
+		}
+		match := sizeDocumentationPattern.FindStringSubmatch(description.Value[0])
+		if match == nil {
+			continue
+		}
+		buffer, found := index[match[2]]
+		if !found || buffer == i || parameters[buffer].Usage.SizeParameter != "" {
+			continue
+		}
+		unit := "elements"
+		if strings.Contains(strings.ToLower(match[1]+match[3]), "byte") {
+			unit = "bytes"
+		}
+		parameters[buffer].Usage.SizeParameter = parameters[i].Name
+		parameters[buffer].Usage.SizeUnit = unit
+	}
+}
@@ -0,0 +1,110 @@
+// Contains the function to create MacroDeclaration struct
+package macro
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+
+	"github.com/cloakwiss/ntdocs/symbols/function"
+	"github.com/cloakwiss/ntdocs/utils"
+)
+
+// Macro pages use the same `nf-` prefix as functions, like `nf-amsi-amsiresultismalware`, but nothing is exported
+// from any DLL for them. Syntax is shown either as `#define` or in the form of a function:
+//
+//	void AmsiResultIsMalware(
+//	  [in] r
+//	);
+type MacroDeclaration struct {
+	Name string
+	// Parameters of function like macro, nil for object like macro
+	Parameters []string
+	// Replacement list of `#define`, empty when page does not show it
+	Body string
+	// Function form of the macro, parameters are often written without type. Nil when page only shows `#define`
+	Signature *function.FunctionDeclaration
+}
+
+// This type will be used to match the schema of database, same as function's
+type MacroDeclarationForInsertion struct {
+	MacroDeclaration
+	Description, Requirements string
+	ParsedRequirements        utils.Requirements
+	ParameterDescription      utils.AssociativeArray[string, []string]
+	RenderedDescription       utils.Rendered
+	RenderedParameters        []utils.Rendered
+}
+
+var (
+	ErrorNoMacroFound = errors.New("No macro found in syntax block")
+
+	definePattern = regexp.MustCompile(`(?m)^\s*#\s*define\s`)
+)
+
+func getString(node *tree_sitter.Node, code []byte) string {
+	return string(code[node.StartByte():node.EndByte()])
+}
+
+// True when syntax of the page is a `#define`, used for pages whose Symbol type is not `macro`. Title of the page
+// (`... macro (amsi.h)`) is not in the html stored by scraper, so a macro shown only in the form of a function is
+// not detected and is known only by its Symbol type.
+func IsMacroPage(mainContent *goquery.Selection) bool {
+	return definePattern.MatchString(mainContent.Find("h2#syntax").NextUntil("h2").Text())
+}
+
+func HandleSyntaxSection(parser *tree_sitter.Parser, syntax string) (MacroDeclaration, error) {
+	var decl MacroDeclaration
+	if strings.Contains(syntax, "#define") {
+		code := []byte(syntax)
+		tree := parser.Parse(code, nil)
+		defer tree.Close()
+		rootNode := tree.RootNode()
+
+		for _, node := range rootNode.NamedChildren(rootNode.Walk()) {
+			switch node.Kind() {
+			case "preproc_def", "preproc_function_def":
+				decl.Name = getString(node.ChildByFieldName("name"), code)
+				if value := node.ChildByFieldName("value"); value != nil {
+					decl.Body = strings.TrimSpace(getString(value, code))
+				}
+				if parameters := node.ChildByFieldName("parameters"); parameters != nil {
+					decl.Parameters = make([]string, 0, parameters.NamedChildCount())
+					for _, parameter := range parameters.Children(parameters.Walk()) {
+						if parameter.IsNamed() || getString(&parameter, code) == "..." {
+							decl.Parameters = append(decl.Parameters, getString(&parameter, code))
+						}
+					}
+				}
+			}
+			if decl.Name != "" {
+				break
+			}
+		}
+	}
+
+	if signature, er := function.HandleFunctionSyntax(parser, syntax); er == nil {
+		// Parameter without type like `[in] r` is parsed as type
+		for i, parameter := range signature.Parameters {
+			if parameter.Name == "" && !strings.ContainsAny(parameter.TypeHint, " *") {
+				signature.Parameters[i].Name, signature.Parameters[i].TypeHint = parameter.TypeHint, ""
+			}
+		}
+		decl.Signature = &signature
+		if decl.Name == "" {
+			decl.Name = signature.Name
+			decl.Parameters = make([]string, 0, len(signature.Parameters))
+			for _, parameter := range signature.Parameters {
+				decl.Parameters = append(decl.Parameters, parameter.Name)
+			}
+		}
+	}
+
+	if decl.Name == "" {
+		return MacroDeclaration{}, ErrorNoMacroFound
+	}
+	return decl, nil
+}
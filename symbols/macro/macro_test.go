@@ -0,0 +1,91 @@
+package macro_test
+
+import (
+	"bufio"
+	"slices"
+	"strings"
+	"testing"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+	tree_sitter_c "github.com/tree-sitter/tree-sitter-c/bindings/go"
+
+	"github.com/cloakwiss/ntdocs/symbols/macro"
+	"github.com/cloakwiss/ntdocs/utils"
+)
+
+func TestMacro(t *testing.T) {
+	var data string = `<div class="content"><p>Determines if the result of a scan indicates that the content should be blocked.</p>
+<h2 id="syntax">Syntax</h2>
+<pre><code class="lang-cpp">void AmsiResultIsMalware(
+  [in] r
+);
+</code></pre>
+<h2 id="parameters">Parameters</h2>
+<p><code>[in] r</code></p>
+<p>The AMSI_RESULT returned by the scan.</p>
+</div>`
+
+	parser := tree_sitter.NewParser()
+	defer parser.Close()
+	parser.SetLanguage(tree_sitter.NewLanguage(tree_sitter_c.Language()))
+
+	mainContent := utils.GetMainContent(bufio.NewReader(strings.NewReader(data)))
+	// Only the Symbol type tells that it is a macro
+	if macro.IsMacroPage(mainContent) {
+		t.Error("Macro in the form of function is detected without #define")
+	}
+	content := utils.GetAllSection(mainContent)
+	decl, er := macro.HandleSyntaxSection(parser, content["syntax"][0].Text())
+	if er != nil {
+		t.Fatal(er)
+	}
+	if decl.Name != "AmsiResultIsMalware" || !slices.Equal(decl.Parameters, []string{"r"}) || decl.Signature == nil {
+		t.Errorf("Wrong declaration: %+v", decl)
+	}
+	if decl.Signature.Parameters[0].Name != "r" || decl.Signature.Parameters[0].TypeHint != "" {
+		t.Errorf("Untyped parameter is wrong: %+v", decl.Signature.Parameters[0])
+	}
+
+	cases := []struct {
+		syntax, name, body string
+		parameters         []string
+	}{
+		{"#define AmsiResultIsMalware(r) ((r) >= AMSI_RESULT_DETECTED)\n", "AmsiResultIsMalware", "((r) >= AMSI_RESULT_DETECTED)", []string{"r"}},
+		{"#define MAKEWORD(a, b)      ((WORD)(((BYTE)(((DWORD_PTR)(a)) & 0xff)) | ((WORD)((BYTE)(((DWORD_PTR)(b)) & 0xff))) << 8))\n",
+			"MAKEWORD", "((WORD)(((BYTE)(((DWORD_PTR)(a)) & 0xff)) | ((WORD)((BYTE)(((DWORD_PTR)(b)) & 0xff))) << 8))", []string{"a", "b"}},
+		{"#define INVALID_FILE_SIZE ((DWORD)0xFFFFFFFF)\n", "INVALID_FILE_SIZE", "((DWORD)0xFFFFFFFF)", nil},
+	}
+	for _, c := range cases {
+		decl, er := macro.HandleSyntaxSection(parser, c.syntax)
+		if er != nil {
+			t.Fatal(er)
+		}
+		if decl.Name != c.name || decl.Body != c.body || !slices.Equal(decl.Parameters, c.parameters) {
+			t.Errorf("Expected: %s %s %v, found: %+v", c.name, c.body, c.parameters, decl)
+		}
+	}
+}
+
+func TestMacroPage(t *testing.T) {
+	var data string = `<div class="content"><p>The MAKEWORD macro creates a WORD value by concatenating the specified values.</p>
+<h2 id="syntax">Syntax</h2>
+<pre><code class="lang-cpp">#define MAKEWORD(a, b) ((WORD)(((BYTE)(((DWORD_PTR)(a)) &amp; 0xff)) | ((WORD)((BYTE)(((DWORD_PTR)(b)) &amp; 0xff))) &lt;&lt; 8))
+</code></pre>
+</div>`
+	if !macro.IsMacroPage(utils.GetMainContent(bufio.NewReader(strings.NewReader(data)))) {
+		t.Error("Page with #define is not detected as macro")
+	}
+
+	data = `<div class="content"><h2 id="syntax">Syntax</h2>
+<pre><code class="lang-cpp">LPVOID VirtualAlloc(
+  [in, optional] LPVOID lpAddress,
+  [in]           SIZE_T dwSize,
+  [in]           DWORD  flAllocationType,
+  [in]           DWORD  flProtect
+);
+</code></pre>
+</div>`
+	if macro.IsMacroPage(utils.GetMainContent(bufio.NewReader(strings.NewReader(data)))) {
+		t.Error("Function is detected as macro")
+	}
+}
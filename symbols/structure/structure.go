@@ -3,9 +3,12 @@ package structure
 import (
 	"errors"
 	"fmt"
-	"log"
+	"slices"
+	"strings"
 
 	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+
+	"github.com/cloakwiss/ntdocs/utils"
 )
 
 // This should be handled in structure stuff, but it was found int WinTypes
@@ -38,18 +41,41 @@ const Query string = `WITH ttypes as (select datatype from FunctionParameters gr
 		END AS n
 	FROM ttypes),
 	completed as (select symbolName from RawHTML)
-select Symbol.* from Symbol JOIN splits ON splits.n = Symbol.name WHERE Symbol.type IS 'structure' AND Symbol.name NOT IN completed;`
+select Symbol.* from Symbol JOIN splits ON splits.n = Symbol.name WHERE Symbol.type IN ('structure', 'union') AND Symbol.name NOT IN completed;`
 
 type (
 	StructDeclaration struct {
+		Names []string
+		Aggregate
+		// Filled from members section of the page, keyed by name of the member
+		MemberConstants     utils.AssociativeArray[string, []utils.ValueDefinition]
+		MemberDocumentation utils.AssociativeArray[string, utils.Documentation]
+		// Filled from description and requirements sections of the page
+		Description        utils.Documentation
+		Requirements       string
+		ParsedRequirements utils.Requirements
+	}
+
+	// Body of a struct or union, nested anonymous aggregates are also represented by it
+	Aggregate struct {
+		// "struct" or "union"
+		Kind string
+		// Tag of the aggregate, empty for anonymous ones
 		StructName string
-		Names      []string
-		Fields     []DatatypeNamePair
+		Fields     []Field
 	}
 
-	DatatypeNamePair struct {
+	Field struct {
+		// For nested aggregate with body this is "struct" or "union"
 		Datatype string
-		Name     string
+		// Empty for the anonymous nested aggregate
+		Name string
+		// Sizes of array declarator as written i.e. `MAX_PATH` in `WCHAR name[MAX_PATH]`, outermost first
+		Dimensions []string
+		// Width of the bit field as written, empty when not a bit field
+		BitWidth string
+		// Only present for nested struct or union with a body
+		Inner *Aggregate
 	}
 )
 
@@ -57,52 +83,220 @@ var (
 	ErrorSomeNewNode = errors.New("Some new ndoe: ")
 )
 
+// Array dimensions in the C syntax i.e. `[MAX_PATH][2]`
+func (f Field) DimensionSuffix() string {
+	var suffix strings.Builder
+	for _, d := range f.Dimensions {
+		suffix.WriteString("[" + d + "]")
+	}
+	return suffix.String()
+}
+
 func getString(node *tree_sitter.Node, code []byte) string {
 	return string(code[node.StartByte():node.EndByte()])
 }
 
+// Syntax is either a typedef of the aggregate, which may be followed by typedefs of its names like `typedef A *PA;`,
+// or a bare `struct _A { ... };` whose tag is the name
 func HandleSyntaxSection(tree *tree_sitter.Tree, code []byte) (StructDeclaration, error) {
 	rootNode := tree.RootNode()
-	if rootNode.ChildCount() > 1 {
-		log.Panicln("Unexpected case")
-	}
-	rootNode = rootNode.Child(0)
 
 	var structDecl StructDeclaration
 
-	for _, node := range rootNode.Children(rootNode.Walk()) {
-		switch node.Kind() {
+	for _, node := range rootNode.NamedChildren(rootNode.Walk()) {
+		var er error
+		switch {
+		case node.Kind() == "comment":
+		case structDecl.Kind != "":
+			er = handleAlias(&node, code, &structDecl)
+		case node.Kind() == "struct_specifier", node.Kind() == "union_specifier":
+			structDecl.Aggregate, er = handleAggregate(&node, code)
+			if structDecl.StructName != "" {
+				structDecl.Names = append(structDecl.Names, structDecl.StructName)
+			}
+		case node.Kind() == "type_definition":
+			er = handleTypedef(&node, code, &structDecl)
+		default:
+			er = fmt.Errorf("%w : %s", ErrorSomeNewNode, node.Kind())
+		}
+		if er != nil {
+			return StructDeclaration{}, er
+		}
+	}
+	if len(structDecl.Names) == 0 {
+		return StructDeclaration{}, fmt.Errorf("%w : no struct or union is named", ErrorSomeNewNode)
+	}
+	return structDecl, nil
+}
+
+func handleTypedef(node *tree_sitter.Node, code []byte, structDecl *StructDeclaration) error {
+	for _, child := range node.Children(node.Walk()) {
+		switch child.Kind() {
 		// look for the name and go inside for field
-		case "struct_specifier":
-			inner := node.ChildByFieldName("body")
-			structDecl.StructName = getString(node.ChildByFieldName("name"), code)
-			if er := handleStruct(inner, &structDecl, code); er != nil {
-				return StructDeclaration{}, er
+		case "struct_specifier", "union_specifier":
+			aggregate, er := handleAggregate(&child, code)
+			if er != nil {
+				return er
 			}
+			structDecl.Aggregate = aggregate
 
 		case "type_identifier", "pointer_declarator":
-			structDecl.Names = append(structDecl.Names, getString(&node, code))
+			structDecl.Names = append(structDecl.Names, getString(&child, code))
 
 		case "typedef", ";", ",":
 		default:
-			return StructDeclaration{}, fmt.Errorf("%w : %s", ErrorSomeNewNode, node.Kind())
+			return fmt.Errorf("%w : %s", ErrorSomeNewNode, child.Kind())
+		}
+	}
+	return nil
+}
 
+// Typedef after the aggregate, only of a name of it like `typedef A *PA;` or `typedef struct _A *PA;`
+func handleAlias(node *tree_sitter.Node, code []byte, structDecl *StructDeclaration) error {
+	if node.Kind() != "type_definition" {
+		return fmt.Errorf("%w : %s after %s", ErrorSomeNewNode, node.Kind(), structDecl.Kind)
+	}
+	aliased := strings.Join(strings.Fields(getString(node.ChildByFieldName("type"), code)), " ")
+	if aliased != structDecl.Kind+" "+structDecl.StructName && !slices.Contains(structDecl.Names, aliased) {
+		return fmt.Errorf("%w : typedef of %s", ErrorSomeNewNode, aliased)
+	}
+	for i, child := range node.Children(node.Walk()) {
+		if node.FieldNameForChild(uint32(i)) == "declarator" {
+			structDecl.Names = append(structDecl.Names, getString(&child, code))
 		}
 	}
-	return structDecl, nil
+	return nil
 }
 
-func handleStruct(node *tree_sitter.Node, structDecl *StructDeclaration, code []byte) error {
-	for _, field := range node.Children(node.Walk()) {
+func handleAggregate(node *tree_sitter.Node, code []byte) (Aggregate, error) {
+	aggregate := Aggregate{Kind: "struct"}
+	if node.Kind() == "union_specifier" {
+		aggregate.Kind = "union"
+	}
+	if name := node.ChildByFieldName("name"); name != nil {
+		aggregate.StructName = getString(name, code)
+	}
+	body := node.ChildByFieldName("body")
+	if body == nil {
+		return Aggregate{}, fmt.Errorf("%w : %s without body", ErrorSomeNewNode, aggregate.Kind)
+	}
+
+	for _, field := range body.Children(body.Walk()) {
 		switch field.Kind() {
 		case "field_declaration":
-			structDecl.Fields = append(structDecl.Fields, DatatypeNamePair{
-				Datatype: getString(field.ChildByFieldName("type"), code),
-				Name:     getString(field.ChildByFieldName("declarator"), code),
-			})
-		case "{", "}":
+			fields, er := handleField(&field, code)
+			if er != nil {
+				return Aggregate{}, er
+			}
+			aggregate.Fields = append(aggregate.Fields, fields...)
+		case "{", "}", "comment":
+		default:
+			return Aggregate{}, fmt.Errorf("%w : %s", ErrorSomeNewNode, field.Kind())
+		}
+	}
+	return aggregate, nil
+}
+
+// One declaration can declare multiple fields like `DWORD x : 4, y : 2;` all of them are returned
+func handleField(node *tree_sitter.Node, code []byte) ([]Field, error) {
+	var (
+		qualifiers []string
+		datatype   string
+		inner      *Aggregate
+		fields     []Field
+	)
+	for i, child := range node.Children(node.Walk()) {
+		switch {
+		case child.Kind() == "type_qualifier":
+			qualifiers = append(qualifiers, getString(&child, code))
+
+		case node.FieldNameForChild(uint32(i)) == "type":
+			kind := child.Kind()
+			if (kind == "struct_specifier" || kind == "union_specifier") && child.ChildByFieldName("body") != nil {
+				aggregate, er := handleAggregate(&child, code)
+				if er != nil {
+					return nil, er
+				}
+				inner = &aggregate
+				datatype = aggregate.Kind
+			} else {
+				datatype = getString(&child, code)
+			}
+			if len(qualifiers) > 0 {
+				datatype = strings.Join(qualifiers, " ") + " " + datatype
+			}
+
+		case node.FieldNameForChild(uint32(i)) == "declarator":
+			fields = append(fields, handleDeclarator(&child, code, datatype, inner))
+
+		case child.Kind() == "bitfield_clause":
+			if len(fields) == 0 {
+				return nil, fmt.Errorf("%w : bit field without declarator", ErrorSomeNewNode)
+			}
+			fields[len(fields)-1].BitWidth = getString(child.NamedChild(0), code)
+
+		case child.Kind() == ";" || child.Kind() == "," || child.Kind() == "comment":
+		default:
+			return nil, fmt.Errorf("%w : %s", ErrorSomeNewNode, child.Kind())
+		}
+	}
+	// Anonymous nested aggregate like `union { ... };`
+	if len(fields) == 0 {
+		fields = append(fields, Field{Datatype: datatype, Inner: inner})
+	}
+	return fields, nil
+}
+
+func handleDeclarator(node *tree_sitter.Node, code []byte, datatype string, inner *Aggregate) Field {
+	var (
+		field = Field{Inner: inner}
+		stars string
+	)
+	for current := node; current != nil; {
+		switch current.Kind() {
+		case "field_identifier", "identifier", "type_identifier":
+			field.Name = getString(current, code)
+			current = nil
+		case "pointer_declarator":
+			stars += "*"
+			current = current.ChildByFieldName("declarator")
+		case "array_declarator":
+			size := ""
+			if s := current.ChildByFieldName("size"); s != nil {
+				size = getString(s, code)
+			}
+			// Outer most array declarator is the last dimension written
+			field.Dimensions = append([]string{size}, field.Dimensions...)
+			current = current.ChildByFieldName("declarator")
 		default:
-			return fmt.Errorf("%w : %s", ErrorSomeNewNode, node.Kind())
+			// Function pointers and other complex declarators are kept as written, without the name
+			full := getString(node, code)
+			if name := findIdentifier(node); name != nil {
+				field.Name = getString(name, code)
+				full = string(code[node.StartByte():name.StartByte()]) + string(code[name.EndByte():node.EndByte()])
+			}
+			field.Datatype = strings.Join(strings.Fields(datatype+" "+full), " ")
+			field.Dimensions = nil
+			return field
+		}
+	}
+	field.Datatype = datatype
+	if stars != "" {
+		field.Datatype += " " + stars
+	}
+	return field
+}
+
+func findIdentifier(node *tree_sitter.Node) *tree_sitter.Node {
+	switch node.Kind() {
+	case "field_identifier", "identifier", "type_identifier":
+		return node
+	}
+	for i := range node.NamedChildCount() {
+		if child := node.NamedChild(i); child.Kind() != "parameter_list" {
+			if found := findIdentifier(child); found != nil {
+				return found
+			}
 		}
 	}
 	return nil
@@ -1,7 +1,136 @@
 package structure_test
 
-import "testing"
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/k0kubun/pp/v3"
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+	tree_sitter_c "github.com/tree-sitter/tree-sitter-c/bindings/go"
+
+	"github.com/cloakwiss/ntdocs/symbols/structure"
+)
 
 func TestGetStructure(t *testing.T) {
 
 }
+
+func parse(t *testing.T, syntax string) structure.StructDeclaration {
+	parser := tree_sitter.NewParser()
+	defer parser.Close()
+	parser.SetLanguage(tree_sitter.NewLanguage(tree_sitter_c.Language()))
+
+	code := []byte(syntax)
+	tree := parser.Parse(code, nil)
+	defer tree.Close()
+
+	decl, er := structure.HandleSyntaxSection(tree, code)
+	if er != nil {
+		t.Fatal(er)
+	}
+	pp.Println(decl)
+	return decl
+}
+
+func TestUnion(t *testing.T) {
+	decl := parse(t, `typedef union _LARGE_INTEGER {
+  struct {
+    DWORD LowPart;
+    LONG  HighPart;
+  } DUMMYSTRUCTNAME;
+  struct {
+    DWORD LowPart;
+    LONG  HighPart;
+  } u;
+  LONGLONG QuadPart;
+} LARGE_INTEGER;`)
+
+	if decl.Kind != "union" || decl.StructName != "_LARGE_INTEGER" || decl.Names[0] != "LARGE_INTEGER" {
+		t.Fatalf("Wrong names: %+v", decl)
+	}
+	if len(decl.Fields) != 3 {
+		t.Fatalf("Expected 3 members found %d", len(decl.Fields))
+	}
+	u := decl.Fields[1]
+	if u.Name != "u" || u.Inner == nil || u.Inner.Kind != "struct" || len(u.Inner.Fields) != 2 || u.Inner.Fields[1].Name != "HighPart" {
+		t.Errorf("Wrong nested member: %+v", u)
+	}
+	if decl.Fields[2].Datatype != "LONGLONG" || decl.Fields[2].Inner != nil {
+		t.Errorf("Wrong member: %+v", decl.Fields[2])
+	}
+}
+
+func TestDeclarators(t *testing.T) {
+	decl := parse(t, `typedef struct tagSAMPLE {
+  DWORD type;
+  union {
+    MOUSEINPUT mi;
+    KEYBDINPUT ki;
+  };
+  DWORD fFlag : 1, fRest : 31;
+  WCHAR szName[MAX_PATH][2];
+  const CHAR **ppNames;
+} SAMPLE, *PSAMPLE;`)
+
+	if decl.Kind != "struct" || len(decl.Names) != 2 || decl.Names[1] != "*PSAMPLE" {
+		t.Fatalf("Wrong names: %+v", decl)
+	}
+	expected := []struct {
+		datatype, name, bitWidth, dimensions string
+	}{
+		{"DWORD", "type", "", ""},
+		{"union", "", "", ""},
+		{"DWORD", "fFlag", "1", ""},
+		{"DWORD", "fRest", "31", ""},
+		{"WCHAR", "szName", "", "[MAX_PATH][2]"},
+		{"const CHAR **", "ppNames", "", ""},
+	}
+	if len(decl.Fields) != len(expected) {
+		t.Fatalf("Expected %d members found %d", len(expected), len(decl.Fields))
+	}
+	for i, e := range expected {
+		f := decl.Fields[i]
+		if f.Datatype != e.datatype || f.Name != e.name || f.BitWidth != e.bitWidth || f.DimensionSuffix() != e.dimensions {
+			t.Errorf("Member %d: expected %+v found %+v", i, e, f)
+		}
+	}
+	if decl.Fields[1].Inner == nil || len(decl.Fields[1].Inner.Fields) != 2 {
+		t.Errorf("Anonymous union not parsed: %+v", decl.Fields[1])
+	}
+}
+
+func TestSyntaxShapes(t *testing.T) {
+	decl := parse(t, `struct sockaddr {
+  u_short sa_family;
+  char    sa_data[14];
+};`)
+	if decl.Kind != "struct" || decl.StructName != "sockaddr" || len(decl.Names) != 1 || decl.Names[0] != "sockaddr" || len(decl.Fields) != 2 {
+		t.Errorf("Wrong bare struct: %+v", decl)
+	}
+
+	decl = parse(t, `typedef struct _A {
+  int x;
+} A;
+typedef A *PA;
+typedef struct _A *LPA;`)
+	if decl.StructName != "_A" || len(decl.Fields) != 1 || !slices.Equal(decl.Names, []string{"A", "*PA", "*LPA"}) {
+		t.Errorf("Wrong names of typedefs: %+v", decl)
+	}
+
+	parser := tree_sitter.NewParser()
+	defer parser.Close()
+	parser.SetLanguage(tree_sitter.NewLanguage(tree_sitter_c.Language()))
+	for _, syntax := range []string{
+		"typedef struct _A { int x; } A;\ntypedef DWORD B;",
+		"struct { int x; };",
+		"typedef struct _A { int x; } A;\nint f(void);",
+	} {
+		code := []byte(syntax)
+		tree := parser.Parse(code, nil)
+		if _, er := structure.HandleSyntaxSection(tree, code); !errors.Is(er, structure.ErrorSomeNewNode) {
+			t.Errorf("%q: expected ErrorSomeNewNode, found %v", syntax, er)
+		}
+		tree.Close()
+	}
+}
@@ -0,0 +1,240 @@
+// Parsing of the toc.json files of the documentation, the root TOC of win32 API which lists the headers and the
+// TOC of each header which lists its symbols
+package toc
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/cloakwiss/ntdocs/utils"
+)
+
+// Path of TOC of the whole win32 API, headers are listed under the technologies in it
+const RootPath = "/windows/win32/api/toc.json"
+
+// Node of toc.json, nodes without href only group their children
+type Node struct {
+	Title    string `json:"toc_title"`
+	Href     string `json:"href"`
+	Children []Node `json:"children"`
+}
+
+// Kind of the symbol as stored in type column of Symbol table
+type Kind string
+
+const (
+	KindFunction    Kind = "function"
+	KindCallback    Kind = "callback function"
+	KindStructure   Kind = "structure"
+	KindUnion       Kind = "union"
+	KindEnumeration Kind = "enumeration"
+	KindMacro       Kind = "macro"
+	KindClass       Kind = "class"
+	KindInterface   Kind = "interface"
+	KindMethod      Kind = "method"
+)
+
+// Words ending the title for each kind, longer ones first so `callback function` is not taken as a function
+var titleSuffixes = []struct {
+	suffix string
+	kind   Kind
+}{
+	{"callback function", KindCallback},
+	{"callback", KindCallback},
+	{"function", KindFunction},
+	{"structure", KindStructure},
+	{"struct", KindStructure},
+	{"union", KindUnion},
+	{"enumeration", KindEnumeration},
+	{"enum", KindEnumeration},
+	{"macro", KindMacro},
+	{"class", KindClass},
+	{"interface", KindInterface},
+	{"method", KindMethod},
+}
+
+// Prefix of the page name for each kind, used when title does not end with the kind.
+// Functions, methods and macros share `nf-` so it is decided by the name.
+var pagePrefixes = map[string]Kind{
+	"nc": KindCallback,
+	"ns": KindStructure,
+	"ne": KindEnumeration,
+	"nl": KindClass,
+	"nn": KindInterface,
+	"nf": KindFunction,
+}
+
+var (
+	// Header appended to some titles i.e. `VirtualAlloc function (memoryapi.h)`
+	headerSuffix = regexp.MustCompile(`\s*\([^()]*\)$`)
+	pagePrefix   = regexp.MustCompile(`(?:^|/)(n[a-z])-[^/]*$`)
+)
+
+type Symbol struct {
+	Name string
+	Kind Kind
+	// Path of the page in the form of Symbol table i.e. `/windows/win32/api/memoryapi/nf-memoryapi-virtualalloc`
+	Path string
+}
+
+// Node which cannot be used, Trail is titles of the nodes from the top of TOC till it
+type NodeError struct {
+	Trail []string
+	Href  string
+	Err   error
+}
+
+func (e NodeError) Error() string {
+	return fmt.Sprintf("%s (%s): %v", strings.Join(e.Trail, " > "), e.Href, e.Err)
+}
+
+func (e NodeError) Unwrap() error {
+	return e.Err
+}
+
+// Symbols of the header, nodes which cannot be classified are in Errors instead
+type Header struct {
+	Name    string
+	Symbols []Symbol
+	Errors  []NodeError
+}
+
+// Header listed in root TOC and the url of its toc.json
+type HeaderLink struct {
+	Name, Url string
+}
+
+type Root struct {
+	Headers []HeaderLink
+	Errors  []NodeError
+}
+
+// Name and kind from the title of node, Kind is empty when it cannot be found
+func Classify(title, href string) (name string, kind Kind) {
+	title = strings.Join(strings.Fields(headerSuffix.ReplaceAllString(strings.TrimSpace(title), "")), " ")
+	lowered := strings.ToLower(title)
+	for _, s := range titleSuffixes {
+		if rest, found := strings.CutSuffix(lowered, " "+s.suffix); found && rest != "" {
+			name, kind = title[:len(rest)], s.kind
+			break
+		}
+	}
+	if name == "" {
+		name = title
+	}
+
+	if kind == "" {
+		if match := pagePrefix.FindStringSubmatch(strings.ToLower(href)); match != nil {
+			kind = pagePrefixes[match[1]]
+		}
+	}
+	// Methods are listed with their interface or class i.e. `IUnknown::QueryInterface method`
+	if kind == KindFunction && strings.Contains(name, "::") {
+		kind = KindMethod
+	}
+	if strings.ContainsAny(name, " \t") {
+		return name, ""
+	}
+	return name, kind
+}
+
+// Symbols listed in the toc.json of a header at tocUrl. Nested subtrees are followed, so the methods listed under
+// an interface are found too. Error is returned only when the file itself cannot be used.
+func ParseHeader(input []byte, tocUrl string) (header Header, er error) {
+	var root Node
+	if er := json.Unmarshal(input, &root); er != nil {
+		return header, fmt.Errorf("cannot parse toc.json: %w", er)
+	}
+	header.Name = strings.TrimSpace(root.Title)
+	if header.Name == "" {
+		return header, fmt.Errorf("toc.json has no title")
+	}
+	// Path of toc.json in the form of Symbol table, so its links resolve to same form
+	base, internal := utils.ResolveLink("/", tocUrl)
+	if !internal {
+		return header, fmt.Errorf("toc.json of %s is not on the documentation: %s", header.Name, tocUrl)
+	}
+	overview := base[:strings.LastIndex(base, "/")]
+
+	var walk func(trail []string, nodes []Node)
+	walk = func(trail []string, nodes []Node) {
+		for _, node := range nodes {
+			trail := append(trail[:len(trail):len(trail)], strings.TrimSpace(node.Title))
+			if node.Href != "" {
+				symbol, er := symbolOf(node, base, overview)
+				if er != nil {
+					header.Errors = append(header.Errors, NodeError{Trail: trail, Href: node.Href, Err: er})
+				} else if symbol.Path != overview {
+					header.Symbols = append(header.Symbols, symbol)
+				}
+			}
+			walk(trail, node.Children)
+		}
+	}
+	walk([]string{header.Name}, root.Children)
+	return header, nil
+}
+
+func symbolOf(node Node, base, overview string) (Symbol, error) {
+	path, internal := utils.ResolveLink(base, node.Href)
+	if !internal {
+		return Symbol{}, fmt.Errorf("link is not to the documentation")
+	}
+	if path == overview {
+		return Symbol{Path: path}, nil
+	}
+	name, kind := Classify(node.Title, path)
+	if name == "" {
+		return Symbol{}, fmt.Errorf("node has no title")
+	}
+	if kind == "" {
+		return Symbol{}, fmt.Errorf("cannot find the kind of %q", node.Title)
+	}
+	return Symbol{Name: name, Kind: kind, Path: path}, nil
+}
+
+// Headers listed in root TOC at rootUrl, in the order of TOC and without duplicates. Nodes titled as header
+// i.e. `memoryapi.h` are taken as headers and their href is the directory containing their toc.json.
+func ParseRoot(input []byte, rootUrl string) (root Root, er error) {
+	var full struct {
+		Items []Node `json:"items"`
+		Node
+	}
+	if er := json.Unmarshal(input, &full); er != nil {
+		return root, fmt.Errorf("cannot parse root TOC: %w", er)
+	}
+	base, er := url.Parse(rootUrl)
+	if er != nil {
+		return root, fmt.Errorf("cannot parse root TOC url: %w", er)
+	}
+
+	seen := make(map[string]bool)
+	var walk func(trail []string, nodes []Node)
+	walk = func(trail []string, nodes []Node) {
+		for _, node := range nodes {
+			title := strings.TrimSpace(node.Title)
+			trail := append(trail[:len(trail):len(trail)], title)
+			if !strings.HasSuffix(strings.ToLower(title), ".h") {
+				walk(trail, node.Children)
+				continue
+			}
+			reference, er := url.Parse(node.Href)
+			if node.Href == "" || er != nil {
+				root.Errors = append(root.Errors, NodeError{Trail: trail, Href: node.Href, Err: fmt.Errorf("header has no usable href")})
+				continue
+			}
+			tocUrl := base.ResolveReference(reference)
+			tocUrl.Path = tocUrl.Path[:strings.LastIndex(tocUrl.Path, "/")+1] + "toc.json"
+			if !seen[title] {
+				seen[title] = true
+				root.Headers = append(root.Headers, HeaderLink{Name: title, Url: tocUrl.String()})
+			}
+		}
+	}
+	walk(nil, full.Items)
+	walk(nil, full.Children)
+	return root, nil
+}
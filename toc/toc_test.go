@@ -0,0 +1,98 @@
+package toc_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/cloakwiss/ntdocs/toc"
+	"github.com/cloakwiss/ntdocs/utils"
+)
+
+func TestParseHeader(t *testing.T) {
+	input := `{"toc_title": "unknwn.h", "children": [
+	{"toc_title": "unknwn.h", "href": "./"},
+	{"toc_title": "Interfaces", "children": [
+		{"toc_title": "IClassFactory interface", "href": "nn-unknwn-iclassfactory", "children": [
+			{"toc_title": "IClassFactory::CreateInstance method (unknwn.h)", "href": "nf-unknwn-iclassfactory-createinstance"}
+		]}
+	]},
+	{"toc_title": "LPFNCANUNLOADNOW callback function", "href": "/en-us/windows/win32/api/unknwn/nc-unknwn-lpfncanunloadnow"},
+	{"toc_title": "IID_PPV_ARGS", "href": "nf-unknwn-iid_ppv_args"},
+	{"toc_title": "Using the factory", "href": "using-the-factory"}
+]}`
+	header, er := toc.ParseHeader([]byte(input), "https://learn.microsoft.com/en-us/windows/win32/api/unknwn/toc.json")
+	if er != nil {
+		t.Fatal(er)
+	}
+	if header.Name != "unknwn.h" {
+		t.Errorf("Wrong header name: %s", header.Name)
+	}
+
+	expected := []toc.Symbol{
+		{Name: "IClassFactory", Kind: toc.KindInterface, Path: "/windows/win32/api/unknwn/nn-unknwn-iclassfactory"},
+		{Name: "IClassFactory::CreateInstance", Kind: toc.KindMethod, Path: "/windows/win32/api/unknwn/nf-unknwn-iclassfactory-createinstance"},
+		{Name: "LPFNCANUNLOADNOW", Kind: toc.KindCallback, Path: "/windows/win32/api/unknwn/nc-unknwn-lpfncanunloadnow"},
+		{Name: "IID_PPV_ARGS", Kind: toc.KindFunction, Path: "/windows/win32/api/unknwn/nf-unknwn-iid_ppv_args"},
+	}
+	if !slices.Equal(header.Symbols, expected) {
+		t.Errorf("Wrong symbols:\n%v\nexpected:\n%v", header.Symbols, expected)
+	}
+	if len(header.Errors) != 1 || !slices.Equal(header.Errors[0].Trail, []string{"unknwn.h", "Using the factory"}) {
+		t.Errorf("Wrong errors: %v", header.Errors)
+	}
+
+	if _, er := toc.ParseHeader([]byte(`{"children": []}`), "https://learn.microsoft.com/en-us/windows/win32/api/unknwn/toc.json"); er == nil {
+		t.Error("toc.json without title is parsed")
+	}
+}
+
+func TestClassify(t *testing.T) {
+	cases := []struct {
+		title, href, name string
+		kind              toc.Kind
+	}{
+		{"VirtualAlloc function (memoryapi.h)", "nf-memoryapi-virtualalloc", "VirtualAlloc", toc.KindFunction},
+		{"PTP_WORK_CALLBACK callback function", "nc-winnt-ptp_work_callback", "PTP_WORK_CALLBACK", toc.KindCallback},
+		{"ACCESS_MODE enumeration", "ne-accctrl-access_mode", "ACCESS_MODE", toc.KindEnumeration},
+		{"LARGE_INTEGER union", "ns-winnt-large_integer-r1", "LARGE_INTEGER", toc.KindUnion},
+		{"MEMORY_BASIC_INFORMATION structure", "ns-winnt-memory_basic_information", "MEMORY_BASIC_INFORMATION", toc.KindStructure},
+		{"AmsiResultIsMalware macro", "nf-amsi-amsiresultismalware", "AmsiResultIsMalware", toc.KindMacro},
+		{"Bitmap class", "nl-gdiplusheaders-bitmap", "Bitmap", toc.KindClass},
+		{"Bitmap::GetPixel method", "nf-gdiplusheaders-bitmap-getpixel", "Bitmap::GetPixel", toc.KindMethod},
+		{"ACCESS_MASK", "ns-winnt-access_mask", "ACCESS_MASK", toc.KindStructure},
+		{"Overview of the header", "overview", "Overview of the header", ""},
+	}
+	for _, c := range cases {
+		name, kind := toc.Classify(c.title, c.href)
+		if name != c.name || kind != c.kind {
+			t.Errorf("%q classified as %q %q, expected %q %q", c.title, name, kind, c.name, c.kind)
+		}
+	}
+}
+
+func TestParseRoot(t *testing.T) {
+	input := `{"items": [{"toc_title": "Windows API", "href": "./", "children": [
+	{"toc_title": "Memory Management", "children": [
+		{"toc_title": "memoryapi.h", "href": "./memoryapi/"},
+		{"toc_title": "winbase.h", "href": "winbase/index"}
+	]},
+	{"toc_title": "System Services", "children": [
+		{"toc_title": "memoryapi.h", "href": "./memoryapi/"},
+		{"toc_title": "broken.h"}
+	]}
+]}]}`
+	root, er := toc.ParseRoot([]byte(input), utils.DocumentationUrl(toc.RootPath))
+	if er != nil {
+		t.Fatal(er)
+	}
+	expected := []toc.HeaderLink{
+		{Name: "memoryapi.h", Url: "https://learn.microsoft.com/en-us/windows/win32/api/memoryapi/toc.json"},
+		{Name: "winbase.h", Url: "https://learn.microsoft.com/en-us/windows/win32/api/winbase/toc.json"},
+	}
+	if !slices.Equal(root.Headers, expected) {
+		t.Errorf("Wrong headers: %v", root.Headers)
+	}
+	if len(root.Errors) != 1 || root.Errors[0].Trail[len(root.Errors[0].Trail)-1] != "broken.h" {
+		t.Errorf("Wrong errors: %v", root.Errors)
+	}
+}
@@ -82,9 +82,8 @@ func JoinBlocks(blocks []*goquery.Selection) string {
 	return strings.Join(out, " ")
 }
 
-// Extract key value pairs out of the table
-// at the moment made with only requirements section in mind
-// TODO: But should also work with tables found in some other parts
+// Extract key value pairs out of the table, header rows are skipped.
+// Found is false when any row is not a key value pair, HandleValueTable should be used for such tables
 func HandleTable(table_block *goquery.Selection) (found bool, output AssociativeArray[string, string]) {
 	if !table_block.Is("table") {
 		found = false
@@ -100,13 +99,16 @@ func HandleTable(table_block *goquery.Selection) (found bool, output Associative
 	found = true
 	for i := range children.Length() {
 		table_row := children.Eq(i)
-		table_data := table_row.Find("td")
-		if table_data.Length() == 2 {
+		table_data := table_row.ChildrenFiltered("td")
+		switch table_data.Length() {
+		case 0:
+			// Only `th` in this row
+		case 2:
 			key := strings.Trim(table_data.Eq(0).Text(), " \n")
 			value := strings.Trim(table_data.Eq(1).Text(), " \n")
 			output = append(output, KV[string, string]{Key: key, Value: value})
-		} else {
-			log.Panicln("Cannot operate on multiple values.")
+		default:
+			return false, nil
 		}
 	}
 	return
@@ -123,6 +125,12 @@ func HandleRequriementSectionOfFunction(blocks []*goquery.Selection) (out string
 		err = er
 		return
 	}
+	out = formatRequirements(arr)
+	return
+}
+
+// Flattens the requirements table into JSON like list of single key objects
+func formatRequirements(arr AssociativeArray[string, string]) string {
 	backingbuf := make([]byte, 0, 256)
 	buf := bytes.NewBuffer(backingbuf)
 	buf.WriteRune('[')
@@ -138,8 +146,7 @@ func HandleRequriementSectionOfFunction(blocks []*goquery.Selection) (out string
 		}
 	}
 	buf.WriteRune(']')
-	out = buf.String()
-	return
+	return buf.String()
 }
 func handleRequriementSectionOfFunction(blocks []*goquery.Selection) (table AssociativeArray[string, string], err error) {
 	if len(blocks) == 1 {
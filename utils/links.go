@@ -0,0 +1,103 @@
+// Contains the extraction of the links found in documentation and their resolution to the path of the page
+package utils
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+type Link struct {
+	Text, Href string
+	// Value of `data-linktype` i.e. "absolute-path", "relative-path" or "external"
+	LinkType string
+}
+
+const DocumentationHost = "learn.microsoft.com"
+
+var (
+	// `/en-us/windows/...` all the paths stored in Symbol table are without the locale
+	localePattern      = regexp.MustCompile(`^/[a-z]{2}-[a-z]{2}/`)
+	documentationHosts = map[string]bool{DocumentationHost: true, "docs.microsoft.com": true, "msdn.microsoft.com": true}
+	// Where the pages are fetched from, a local server with fixtures can stand in for the documentation
+	sourceUrl = url.URL{Scheme: "https", Host: DocumentationHost, Path: "/en-us"}
+)
+
+// Fetches the pages from rawUrl instead of the documentation i.e. `http://127.0.0.1:8080/en-us`. Links in the
+// pages and the rendered urls stay on the documentation.
+func SetSourceUrl(rawUrl string) error {
+	source, er := url.Parse(strings.TrimSpace(rawUrl))
+	if er != nil {
+		return fmt.Errorf("cannot parse base url: %w", er)
+	}
+	if (source.Scheme != "http" && source.Scheme != "https") || source.Host == "" {
+		return fmt.Errorf("base url needs http or https and a host: %s", rawUrl)
+	}
+	source.Path = strings.TrimSuffix(source.Path, "/")
+	source.RawQuery, source.Fragment = "", ""
+	sourceUrl = *source
+	return nil
+}
+
+// Url to fetch the page at path returned by ResolveLink
+func SourceUrl(path string) string {
+	return sourceUrl.String() + path
+}
+
+// Collects all the links with `data-linktype` in order, links within the same page i.e. `#remarks` are skipped
+func ExtractLinks(blocks []*goquery.Selection) (links []Link) {
+	for _, block := range blocks {
+		for _, anchor := range block.Filter("a[data-linktype]").AddSelection(block.Find("a[data-linktype]")).EachIter() {
+			href, _ := anchor.Attr("href")
+			linkType, _ := anchor.Attr("data-linktype")
+			if href == "" || linkType == "self-bookmark" || strings.HasPrefix(href, "#") {
+				continue
+			}
+			links = append(links, Link{
+				Text:     strings.Join(strings.Fields(anchor.Text()), " "),
+				Href:     href,
+				LinkType: linkType,
+			})
+		}
+	}
+	return
+}
+
+// Resolves the href found in the page at base (path as stored in Symbol table i.e. `/windows/win32/api/memoryapi/nf-memoryapi-virtualalloc`)
+// to the path of the linked page in same form, so `nf-memoryapi-virtualfreeex`, `/en-us/windows/win32/api/memoryapi/nf-memoryapi-virtualfreeex`
+// and full url all give same path. Urls under the source set by SetSourceUrl give same path too. Internal is false
+// for links to other sites.
+func ResolveLink(base, href string) (path string, internal bool) {
+	baseUrl, er := url.Parse("https://" + DocumentationHost + "/en-us" + base)
+	if er != nil {
+		return "", false
+	}
+	reference, er := url.Parse(strings.TrimSpace(href))
+	if er != nil {
+		return "", false
+	}
+	resolved := baseUrl.ResolveReference(reference)
+	host := strings.ToLower(resolved.Host)
+	if host == strings.ToLower(sourceUrl.Host) && strings.HasPrefix(resolved.Path, sourceUrl.Path+"/") {
+		return NormalizePath(strings.TrimPrefix(resolved.Path, sourceUrl.Path)), true
+	}
+	if !documentationHosts[host] {
+		return "", false
+	}
+	return NormalizePath(resolved.Path), true
+}
+
+// Path of the url in the form of Symbol table, locale and trailing `/` or `.md` are removed
+func NormalizePath(path string) string {
+	path = strings.ToLower(path)
+	path = localePattern.ReplaceAllString(path, "/")
+	return strings.TrimSuffix(strings.TrimSuffix(path, "/"), ".md")
+}
+
+// Absolute url of the path returned by ResolveLink, always on the documentation
+func DocumentationUrl(path string) string {
+	return "https://" + DocumentationHost + "/en-us" + path
+}
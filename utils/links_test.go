@@ -0,0 +1,79 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+func TestSourceUrl(t *testing.T) {
+	previous := sourceUrl
+	defer func() { sourceUrl = previous }()
+
+	const path = "/windows/win32/api/memoryapi/nf-memoryapi-virtualalloc"
+	if url := SourceUrl(path); url != DocumentationUrl(path) {
+		t.Errorf("default source is %s, documentation is %s", url, DocumentationUrl(path))
+	}
+
+	if er := SetSourceUrl("ftp://127.0.0.1"); er == nil {
+		t.Errorf("source without http is accepted")
+	}
+	if er := SetSourceUrl("http://127.0.0.1:8080/fixtures/en-us/"); er != nil {
+		t.Fatal(er)
+	}
+	if url := SourceUrl(path); url != "http://127.0.0.1:8080/fixtures/en-us"+path {
+		t.Errorf("source url is %s", url)
+	}
+	if url := DocumentationUrl(path); url != "https://learn.microsoft.com/en-us"+path {
+		t.Errorf("documentation url changed with source: %s", url)
+	}
+
+	cases := []struct {
+		base, href, path string
+		internal         bool
+	}{
+		{"/", "http://127.0.0.1:8080/fixtures/en-us/windows/win32/api/memoryapi/toc.json", "/windows/win32/api/memoryapi/toc.json", true},
+		{"/", "http://127.0.0.1:8080/fixtures/en-us/windows/win32/api/memoryapi/", "/windows/win32/api/memoryapi", true},
+		{"/", "http://127.0.0.1:8080/fixtures/de-de/windows/win32/api/memoryapi/", "", false},
+		{path, "nf-memoryapi-virtualfree", "/windows/win32/api/memoryapi/nf-memoryapi-virtualfree", true},
+		{path, "https://learn.microsoft.com/en-us/windows/win32/api/memoryapi/nf-memoryapi-virtualfree", "/windows/win32/api/memoryapi/nf-memoryapi-virtualfree", true},
+		{"/", "http://127.0.0.1:8080/other/windows/win32/api/memoryapi/toc.json", "", false},
+		{"/", "http://127.0.0.1:9090/fixtures/en-us/windows/win32/api/memoryapi/toc.json", "", false},
+	}
+	for _, c := range cases {
+		path, internal := ResolveLink(c.base, c.href)
+		if path != c.path || internal != c.internal {
+			t.Errorf("ResolveLink(%q, %q) = %q, %v, expected %q, %v", c.base, c.href, path, internal, c.path, c.internal)
+		}
+	}
+}
+
+func TestResolveLink(t *testing.T) {
+	const base = "/windows/win32/api/memoryapi/nf-memoryapi-virtualalloc"
+	cases := []struct {
+		href, path string
+		internal   bool
+	}{
+		{"nf-memoryapi-virtualfreeex", "/windows/win32/api/memoryapi/nf-memoryapi-virtualfreeex", true},
+		{"/en-us/windows/win32/api/memoryapi/nf-memoryapi-VirtualFreeEx", "/windows/win32/api/memoryapi/nf-memoryapi-virtualfreeex", true},
+		{"../winnt/ns-winnt-memory_basic_information#remarks", "/windows/win32/api/winnt/ns-winnt-memory_basic_information", true},
+		{"https://learn.microsoft.com/en-us/windows/win32/Memory/memory-protection-constants", "/windows/win32/memory/memory-protection-constants", true},
+		{"https://github.com/MicrosoftDocs/sdk-api", "", false},
+	}
+	for _, c := range cases {
+		if path, internal := ResolveLink(base, c.href); path != c.path || internal != c.internal {
+			t.Errorf("%s: expected %q %v, found %q %v", c.href, c.path, c.internal, path, internal)
+		}
+	}
+
+	doc, er := goquery.NewDocumentFromReader(strings.NewReader(`<div class="content"><p><a href="#remarks" data-linktype="self-bookmark">Remarks</a>
+<a href="nf-memoryapi-virtualfreeex" data-linktype="relative-path"><b>VirtualFreeEx</b></a></p></div>`))
+	if er != nil {
+		t.Fatal(er)
+	}
+	links := ExtractLinks([]*goquery.Selection{doc.Find("p")})
+	if len(links) != 1 || links[0].Text != "VirtualFreeEx" || links[0].LinkType != "relative-path" {
+		t.Errorf("Wrong links: %+v", links)
+	}
+}
@@ -0,0 +1,375 @@
+// Contains the renderer of documentation blocks to Markdown and plain text, for the consumers which cannot show html
+package utils
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+)
+
+type Format uint8
+
+const (
+	FormatMarkdown Format = iota + 1
+	FormatText
+)
+
+// Both the forms of the same blocks, html is stored separately as it was before
+type Rendered struct {
+	Markdown, Text string
+}
+
+// Html of the blocks along with its rendered forms
+type Documentation struct {
+	Html string
+	Rendered
+}
+
+func Document(blocks []*goquery.Selection, base string) Documentation {
+	return Documentation{Html: JoinBlocks(blocks), Rendered: RenderBlocks(blocks, base)}
+}
+
+// Renders the blocks as Markdown and plain text, base is the path of the page (as in Symbol table) used for relative links
+func RenderBlocks(blocks []*goquery.Selection, base string) Rendered {
+	return Rendered{
+		Markdown: Render(blocks, base, FormatMarkdown),
+		Text:     Render(blocks, base, FormatText),
+	}
+}
+
+func Render(blocks []*goquery.Selection, base string, format Format) string {
+	var (
+		r     = renderer{base: base, format: format}
+		nodes []*html.Node
+	)
+	for _, block := range blocks {
+		nodes = append(nodes, block.Nodes...)
+	}
+	return strings.Join(r.blocks(nodes), "\n\n")
+}
+
+// Absolute url of the href found in the page at base, fragment is kept. Links to other sites are kept as written.
+func AbsoluteUrl(base, href string) string {
+	path, internal := ResolveLink(base, href)
+	if !internal {
+		return href
+	}
+	absolute := DocumentationUrl(path)
+	if index := strings.Index(href, "#"); index >= 0 {
+		absolute += href[index:]
+	}
+	return absolute
+}
+
+// Marks `<br>` in inline content till the whitespace is collapsed
+const lineBreak = "\x00"
+
+type renderer struct {
+	base   string
+	format Format
+}
+
+func (r renderer) markdown() bool {
+	return r.format == FormatMarkdown
+}
+
+var blockElements = map[string]bool{
+	"p": true, "div": true, "section": true, "blockquote": true, "pre": true, "table": true,
+	"ul": true, "ol": true, "li": true, "dl": true, "dt": true, "dd": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+}
+
+func isBlock(node *html.Node) bool {
+	return node.Type == html.ElementNode && blockElements[node.Data]
+}
+
+func children(node *html.Node) (out []*html.Node) {
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		out = append(out, child)
+	}
+	return
+}
+
+// Renders a sequence of sibling nodes, consecutive inline nodes become a paragraph
+func (r renderer) blocks(nodes []*html.Node) (out []string) {
+	var run []*html.Node
+	flush := func() {
+		if text := r.inlines(run); text != "" {
+			out = append(out, text)
+		}
+		run = run[:0]
+	}
+	for _, node := range nodes {
+		if !isBlock(node) {
+			run = append(run, node)
+			continue
+		}
+		flush()
+		out = append(out, r.block(node)...)
+	}
+	flush()
+	return
+}
+
+func (r renderer) block(node *html.Node) []string {
+	switch node.Data {
+	case "p", "dt":
+		if text := r.inlines(children(node)); text != "" {
+			if node.Data == "dt" && r.markdown() && !strings.HasPrefix(text, "**") {
+				text = "**" + text + "**"
+			}
+			return []string{text}
+		}
+		return nil
+
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		text := r.inlines(children(node))
+		if text == "" {
+			return nil
+		}
+		if r.markdown() {
+			text = strings.Repeat("#", int(node.Data[1]-'0')) + " " + text
+		}
+		return []string{text}
+
+	case "pre":
+		code := strings.Trim(textOf(node), "\n")
+		if !r.markdown() {
+			return []string{code}
+		}
+		return []string{"```" + codeLanguage(node) + "\n" + code + "\n```"}
+
+	case "ul", "ol":
+		var items []string
+		for _, item := range children(node) {
+			if item.Type != html.ElementNode {
+				continue
+			}
+			marker := "- "
+			if node.Data == "ol" {
+				marker = strconv.Itoa(len(items)+1) + ". "
+			}
+			content := strings.Join(r.blocks(children(item)), "\n\n")
+			items = append(items, marker+indent(content, len(marker)))
+		}
+		if len(items) == 0 {
+			return nil
+		}
+		return []string{strings.Join(items, "\n")}
+
+	case "table":
+		return r.table(node)
+
+	default:
+		return r.blocks(children(node))
+	}
+}
+
+func (r renderer) table(node *html.Node) []string {
+	var rows [][]string
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		for _, child := range children(n) {
+			if child.Type != html.ElementNode {
+				continue
+			}
+			switch child.Data {
+			case "tr":
+				var row []string
+				for _, cell := range children(child) {
+					if cell.Type == html.ElementNode && (cell.Data == "td" || cell.Data == "th") {
+						separator := " "
+						if r.markdown() {
+							separator = "<br>"
+						}
+						text := strings.Join(r.blocks(children(cell)), separator)
+						text = strings.ReplaceAll(strings.ReplaceAll(text, "\\\n", "\n"), "\n", separator)
+						if r.markdown() {
+							text = strings.ReplaceAll(text, "|", `\|`)
+						}
+						row = append(row, text)
+					}
+				}
+				if len(row) > 0 {
+					rows = append(rows, row)
+				}
+			case "thead", "tbody", "tfoot":
+				walk(child)
+			}
+		}
+	}
+	walk(node)
+	if len(rows) == 0 {
+		return nil
+	}
+
+	if !r.markdown() {
+		lines := make([]string, 0, len(rows))
+		for _, row := range rows {
+			lines = append(lines, strings.Join(row, " | "))
+		}
+		return []string{strings.Join(lines, "\n")}
+	}
+
+	columns := 0
+	for _, row := range rows {
+		columns = max(columns, len(row))
+	}
+	lines := make([]string, 0, len(rows)+1)
+	for i, row := range rows {
+		for len(row) < columns {
+			row = append(row, "")
+		}
+		lines = append(lines, "| "+strings.Join(row, " | ")+" |")
+		if i == 0 {
+			lines = append(lines, "|"+strings.Repeat(" --- |", columns))
+		}
+	}
+	return []string{strings.Join(lines, "\n")}
+}
+
+// Renders the inline nodes and collapses the whitespace, blocks nested in inline elements are flattened
+func (r renderer) inlines(nodes []*html.Node) string {
+	var builder strings.Builder
+	for _, node := range nodes {
+		r.inline(node, &builder)
+	}
+	text := strings.Join(strings.Fields(builder.String()), " ")
+	breakWith := "\n"
+	if r.markdown() {
+		breakWith = "\\\n"
+	}
+	text = strings.ReplaceAll(text, " "+lineBreak, lineBreak)
+	text = strings.ReplaceAll(text, lineBreak+" ", lineBreak)
+	text = strings.Trim(text, lineBreak+" ")
+	return strings.ReplaceAll(text, lineBreak, breakWith)
+}
+
+func (r renderer) inline(node *html.Node, builder *strings.Builder) {
+	switch node.Type {
+	case html.TextNode:
+		builder.WriteString(node.Data)
+		return
+	case html.ElementNode:
+	default:
+		return
+	}
+
+	// Content of the element, with the whitespace at the ends moved outside of the markup
+	wrap := func(prefix, suffix string) {
+		var inner strings.Builder
+		for _, child := range children(node) {
+			r.inline(child, &inner)
+		}
+		text := inner.String()
+		trimmed := strings.TrimSpace(text)
+		if trimmed == "" {
+			builder.WriteString(text)
+			return
+		}
+		if strings.TrimLeftFunc(text, unicode.IsSpace) != text {
+			builder.WriteString(" ")
+		}
+		builder.WriteString(prefix + trimmed + suffix)
+		if strings.TrimRightFunc(text, unicode.IsSpace) != text {
+			builder.WriteString(" ")
+		}
+	}
+
+	switch node.Data {
+	case "br":
+		builder.WriteString(lineBreak)
+	case "img":
+		builder.WriteString(attribute(node, "alt"))
+	case "b", "strong":
+		if r.markdown() {
+			wrap("**", "**")
+		} else {
+			wrap("", "")
+		}
+	case "i", "em":
+		if r.markdown() {
+			wrap("*", "*")
+		} else {
+			wrap("", "")
+		}
+	case "code":
+		if !r.markdown() {
+			builder.WriteString(textOf(node))
+			return
+		}
+		code := strings.Join(strings.Fields(textOf(node)), " ")
+		fence := "`"
+		if strings.Contains(code, "`") {
+			fence = "``"
+		}
+		if code != "" {
+			builder.WriteString(fence + code + fence)
+		}
+	case "a":
+		href := attribute(node, "href")
+		if !r.markdown() || href == "" {
+			wrap("", "")
+			return
+		}
+		wrap("[", "]("+AbsoluteUrl(r.base, href)+")")
+	default:
+		if isBlock(node) {
+			builder.WriteString(" ")
+			defer builder.WriteString(" ")
+		}
+		for _, child := range children(node) {
+			r.inline(child, builder)
+		}
+	}
+}
+
+func attribute(node *html.Node, key string) string {
+	for _, attr := range node.Attr {
+		if attr.Key == key {
+			return attr.Val
+		}
+	}
+	return ""
+}
+
+func textOf(node *html.Node) string {
+	var builder strings.Builder
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			builder.WriteString(n.Data)
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	walk(node)
+	return strings.ReplaceAll(builder.String(), "\u00a0", " ")
+}
+
+// Language from class like `lang-cpp` of the `<code>` inside `<pre>`
+func codeLanguage(node *html.Node) string {
+	for _, n := range append([]*html.Node{node}, children(node)...) {
+		for _, class := range strings.Fields(attribute(n, "class")) {
+			if language, found := strings.CutPrefix(class, "lang-"); found {
+				return language
+			}
+		}
+	}
+	return ""
+}
+
+func indent(text string, width int) string {
+	padding := strings.Repeat(" ", width)
+	lines := strings.Split(text, "\n")
+	for i := 1; i < len(lines); i++ {
+		if lines[i] != "" {
+			lines[i] = padding + lines[i]
+		}
+	}
+	return strings.Join(lines, "\n")
+}
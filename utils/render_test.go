@@ -0,0 +1,86 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+func TestRender(t *testing.T) {
+	doc, er := goquery.NewDocumentFromReader(strings.NewReader(`<div class="content">
+<p>Reserves, commits, or changes the state of a region of pages in the virtual address space of the calling process.&nbsp;Memory allocated by this function is automatically initialized to zero.</p>
+<p>To allocate memory in the address space of another process, use the <a href="nf-memoryapi-virtualallocex" data-linktype="relative-path"><b>VirtualAllocEx</b></a> function.</p>
+<ul>
+<li>The <i>lpAddress</i> must be <code>NULL</code>.</li>
+<li>Call <b>GetLastError</b>.<br/>Second line.</li>
+</ul>
+<table>
+<thead><tr><th>Value</th><th>Meaning</th></tr></thead>
+<tbody><tr><td><b>MEM_COMMIT</b><br/>0x00001000</td><td><p>Allocates memory.</p></td></tr></tbody>
+</table>
+<pre><code class="lang-cpp">#define X 1
+int y;</code></pre>
+</div>`))
+	if er != nil {
+		t.Fatal(er)
+	}
+	blocks := GetAllSection(doc.Find("div.content").First())["basic-description"]
+	rendered := RenderBlocks(blocks, "/windows/win32/api/memoryapi/nf-memoryapi-virtualalloc")
+
+	markdown := `Reserves, commits, or changes the state of a region of pages in the virtual address space of the calling process. Memory allocated by this function is automatically initialized to zero.
+
+To allocate memory in the address space of another process, use the [**VirtualAllocEx**](https://learn.microsoft.com/en-us/windows/win32/api/memoryapi/nf-memoryapi-virtualallocex) function.
+
+- The *lpAddress* must be ` + "`NULL`" + `.
+- Call **GetLastError**.\
+  Second line.
+
+| Value | Meaning |
+| --- | --- |
+| **MEM_COMMIT**<br>0x00001000 | Allocates memory. |
+
+` + "```cpp\n#define X 1\nint y;\n```"
+	if rendered.Markdown != markdown {
+		t.Errorf("Wrong markdown:\n%s", rendered.Markdown)
+	}
+
+	text := `Reserves, commits, or changes the state of a region of pages in the virtual address space of the calling process. Memory allocated by this function is automatically initialized to zero.
+
+To allocate memory in the address space of another process, use the VirtualAllocEx function.
+
+- The lpAddress must be NULL.
+- Call GetLastError.
+  Second line.
+
+Value | Meaning
+MEM_COMMIT 0x00001000 | Allocates memory.
+
+#define X 1
+int y;`
+	if rendered.Text != text {
+		t.Errorf("Wrong text:\n%s", rendered.Text)
+	}
+}
+
+func TestAbsoluteUrl(t *testing.T) {
+	previous := sourceUrl
+	defer func() { sourceUrl = previous }()
+	if er := SetSourceUrl("http://127.0.0.1:8080/en-us"); er != nil {
+		t.Fatal(er)
+	}
+
+	const base = "/windows/win32/api/memoryapi/nf-memoryapi-virtualalloc"
+	cases := []struct{ href, url string }{
+		{"nf-memoryapi-virtualallocex", "https://learn.microsoft.com/en-us/windows/win32/api/memoryapi/nf-memoryapi-virtualallocex"},
+		{"#remarks", "https://learn.microsoft.com/en-us" + base + "#remarks"},
+		{"../winnt/ns-winnt-memory_basic_information#members", "https://learn.microsoft.com/en-us/windows/win32/api/winnt/ns-winnt-memory_basic_information#members"},
+		{"http://127.0.0.1:8080/en-us/windows/win32/api/memoryapi/nf-memoryapi-virtualfree", "https://learn.microsoft.com/en-us/windows/win32/api/memoryapi/nf-memoryapi-virtualfree"},
+		{"https://github.com/MicrosoftDocs/sdk-api", "https://github.com/MicrosoftDocs/sdk-api"},
+	}
+	for _, c := range cases {
+		if url := AbsoluteUrl(base, c.href); url != c.url {
+			t.Errorf("%s: expected %s, found %s", c.href, c.url, url)
+		}
+	}
+}
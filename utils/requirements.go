@@ -0,0 +1,185 @@
+// Contains the typed form of the requirements table found at the end of most of the pages
+package utils
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+type Requirements struct {
+	MinimumClient, MinimumServer Version
+	TargetPlatform               string
+	// Header declaring the symbol and the headers which should be included instead of it
+	Header       string
+	IncludeChain []string
+	Libraries    []string
+	DLLs         []string
+	APISets      []string
+	// Table as it was found in the page
+	Raw AssociativeArray[string, string]
+}
+
+// Minimum windows version, Major, Minor and Build are of NT kernel i.e. Windows 10, version 1709 is 10.0.16299
+type Version struct {
+	Text                string
+	Release             string
+	Major, Minor, Build int
+	// Text in brackets like "desktop apps | UWP apps"
+	AppTypes []string
+}
+
+// Known is true when the version numbers could be decided from the text
+func (v Version) Known() bool {
+	return v.Major > 0
+}
+
+func (v Version) String() string {
+	if !v.Known() {
+		return ""
+	}
+	return strconv.Itoa(v.Major) + "." + strconv.Itoa(v.Minor) + "." + strconv.Itoa(v.Build)
+}
+
+type ntVersion struct {
+	major, minor, build int
+}
+
+var (
+	clientPattern  = regexp.MustCompile(`Windows\s+(2000|XP|Vista|7|8\.1|8|10|11)\b(?:,?\s+version\s+(\w+))?`)
+	serverPattern  = regexp.MustCompile(`Windows\s+Server\s+(2003\s+R2|2003|2008\s+R2|2008|2012\s+R2|2012|2016|2019|2022|2025)\b(?:,?\s+version\s+(\w+))?`)
+	buildPattern   = regexp.MustCompile(`\b(\d+)\.(\d+)\.(\d+)\b`)
+	buildNoPattern = regexp.MustCompile(`(?i)\bBuild\s+(\d+)\b`)
+	appTypePattern = regexp.MustCompile(`\[([^\]]*)\]`)
+	headerPattern  = regexp.MustCompile(`^\s*([\w./\\-]+)(?:\s*\(include\s+([^)]*)\))?`)
+	listSeparator  = regexp.MustCompile(`\s*(?:;|,|\s+or\s+|\s+and\s+)\s*`)
+
+	releases = map[string]ntVersion{
+		"2000": {5, 0, 2195}, "XP": {5, 1, 2600}, "Vista": {6, 0, 6000}, "7": {6, 1, 7600},
+		"8": {6, 2, 9200}, "8.1": {6, 3, 9600}, "10": {10, 0, 10240}, "11": {10, 0, 22000},
+		"Server 2003": {5, 2, 3790}, "Server 2003 R2": {5, 2, 3790}, "Server 2008": {6, 0, 6001},
+		"Server 2008 R2": {6, 1, 7600}, "Server 2012": {6, 2, 9200}, "Server 2012 R2": {6, 3, 9600},
+		"Server 2016": {10, 0, 14393}, "Server 2019": {10, 0, 17763}, "Server 2022": {10, 0, 20348},
+		"Server 2025": {10, 0, 26100},
+	}
+	// Builds of the feature updates of Windows 10 and 11
+	featureBuilds = map[string]map[string]int{
+		"10": {
+			"1507": 10240, "1511": 10586, "1607": 14393, "1703": 15063, "1709": 16299, "1803": 17134,
+			"1809": 17763, "1903": 18362, "1909": 18363, "2004": 19041, "20H2": 19042, "21H1": 19043,
+			"21H2": 19044, "22H2": 19045,
+		},
+		"11": {"21H2": 22000, "22H2": 22621, "23H2": 22631, "24H2": 26100},
+	}
+)
+
+func ParseVersion(text string, server bool) (version Version) {
+	text = strings.Join(strings.Fields(text), " ")
+	version.Text = text
+	if match := appTypePattern.FindStringSubmatch(text); match != nil {
+		for _, app := range strings.Split(match[1], "|") {
+			if app = strings.TrimSpace(app); app != "" {
+				version.AppTypes = append(version.AppTypes, app)
+			}
+		}
+	}
+
+	var (
+		match  []string
+		prefix string
+	)
+	if server {
+		match, prefix = serverPattern.FindStringSubmatch(text), "Server "
+	} else {
+		match = clientPattern.FindStringSubmatch(text)
+	}
+	var nt ntVersion
+	if match != nil {
+		version.Release = prefix + strings.Join(strings.Fields(match[1]), " ")
+		nt = releases[version.Release]
+		if builds, found := featureBuilds[match[1]]; found && match[2] != "" {
+			if build, found := builds[strings.ToUpper(match[2])]; found {
+				nt.build = build
+			}
+		}
+	}
+	// Explicit build number like `Windows 10 Build 20348` or `10.0.20348` wins
+	if explicit := buildPattern.FindStringSubmatch(text); explicit != nil {
+		nt.major, _ = strconv.Atoi(explicit[1])
+		nt.minor, _ = strconv.Atoi(explicit[2])
+		nt.build, _ = strconv.Atoi(explicit[3])
+	} else if explicit := buildNoPattern.FindStringSubmatch(text); explicit != nil {
+		nt.build, _ = strconv.Atoi(explicit[1])
+		// Without the release i.e. `Windows Build 22000`, every build since Windows 10 is of NT 10.0
+		if nt.major == 0 && nt.build >= releases["10"].build {
+			nt.major, nt.minor = 10, 0
+		}
+	}
+	version.Major, version.Minor, version.Build = nt.major, nt.minor, nt.build
+	return
+}
+
+func splitList(text string) (out []string) {
+	// Remove notes like `(introduced in Windows 8)`
+	for {
+		start := strings.Index(text, "(")
+		end := strings.Index(text, ")")
+		if start < 0 || end < start {
+			break
+		}
+		text = text[:start] + " " + text[end+1:]
+	}
+	for _, item := range listSeparator.Split(strings.TrimSpace(text), -1) {
+		item = strings.Trim(strings.Join(strings.Fields(item), " "), " .")
+		if item != "" {
+			out = append(out, item)
+		}
+	}
+	return
+}
+
+func ParseRequirements(table AssociativeArray[string, string]) (requirements Requirements) {
+	requirements.Raw = table
+	for _, row := range table {
+		key := strings.ToLower(strings.Join(strings.Fields(row.Key), " "))
+		value := strings.Join(strings.Fields(row.Value), " ")
+		switch {
+		case strings.HasPrefix(key, "minimum supported client"):
+			requirements.MinimumClient = ParseVersion(value, false)
+		case strings.HasPrefix(key, "minimum supported server"):
+			requirements.MinimumServer = ParseVersion(value, true)
+		case key == "target platform":
+			requirements.TargetPlatform = value
+		case key == "header":
+			if match := headerPattern.FindStringSubmatch(value); match != nil {
+				requirements.Header = match[1]
+				if match[2] != "" {
+					requirements.IncludeChain = splitList(match[2])
+				}
+			}
+		case key == "library":
+			requirements.Libraries = append(requirements.Libraries, splitList(value)...)
+		case key == "dll":
+			requirements.DLLs = append(requirements.DLLs, splitList(value)...)
+		case strings.HasPrefix(key, "api set"):
+			requirements.APISets = append(requirements.APISets, splitList(value)...)
+		}
+	}
+	return
+}
+
+// Same as the output of HandleRequriementSectionOfFunction
+func (r Requirements) String() string {
+	return formatRequirements(r.Raw)
+}
+
+// Typed version of HandleRequriementSectionOfFunction
+func HandleRequirementsSection(blocks []*goquery.Selection) (Requirements, error) {
+	table, er := handleRequriementSectionOfFunction(blocks)
+	if er != nil {
+		return Requirements{}, er
+	}
+	return ParseRequirements(table), nil
+}
@@ -0,0 +1,57 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseRequirements(t *testing.T) {
+	table := AssociativeArray[string, string]{
+		{Key: "Minimum supported client", Value: "Windows 10, version 1709 [desktop apps | UWP apps]"},
+		{Key: "Minimum supported server", Value: "Windows Server 2003 [desktop apps only]"},
+		{Key: "Target Platform", Value: "Windows"},
+		{Key: "Header", Value: "memoryapi.h (include Windows.h, Memoryapi.h)"},
+		{Key: "Library", Value: "Kernel32.lib; onecore.lib"},
+		{Key: "DLL", Value: "Kernel32.dll"},
+		{Key: "API set", Value: "ext-ms-win-kernel32-package-current-l1-1-0 (introduced in Windows 10, version 10.0.10240)"},
+	}
+	req := ParseRequirements(table)
+	if v := req.MinimumClient; v.Release != "10" || v.String() != "10.0.16299" || len(v.AppTypes) != 2 {
+		t.Errorf("Wrong client version: %+v", v)
+	}
+	if v := req.MinimumServer; v.Release != "Server 2003" || v.String() != "5.2.3790" {
+		t.Errorf("Wrong server version: %+v", v)
+	}
+	if req.Header != "memoryapi.h" || strings.Join(req.IncludeChain, ",") != "Windows.h,Memoryapi.h" {
+		t.Errorf("Wrong header: %s %v", req.Header, req.IncludeChain)
+	}
+	if strings.Join(req.Libraries, ",") != "Kernel32.lib,onecore.lib" || strings.Join(req.DLLs, ",") != "Kernel32.dll" {
+		t.Errorf("Wrong libraries: %v %v", req.Libraries, req.DLLs)
+	}
+	if len(req.APISets) != 1 || req.APISets[0] != "ext-ms-win-kernel32-package-current-l1-1-0" {
+		t.Errorf("Wrong api sets: %v", req.APISets)
+	}
+}
+
+func TestParseVersion(t *testing.T) {
+	cases := []struct {
+		text    string
+		server  bool
+		release string
+		version string
+	}{
+		{"Windows 10, version 1709 [desktop apps | UWP apps]", false, "10", "10.0.16299"},
+		{"Windows 10 Build 20348", false, "10", "10.0.20348"},
+		{"Windows Build 22000", false, "", "10.0.22000"},
+		{"Windows Server Build 20348 [desktop apps only]", true, "", "10.0.20348"},
+		{"Windows 8 [desktop apps only]", false, "8", "6.2.9200"},
+		{"Windows XP Build 2600", false, "XP", "5.1.2600"},
+		{"Build 2600", false, "", ""},
+		{"None supported", false, "", ""},
+	}
+	for _, c := range cases {
+		if v := ParseVersion(c.text, c.server); v.Release != c.release || v.String() != c.version {
+			t.Errorf("%q: expected %q %q, found %q %q", c.text, c.release, c.version, v.Release, v.String())
+		}
+	}
+}
@@ -0,0 +1,107 @@
+// Contains the extractor for `Value | Meaning` tables found in parameter and member documentation
+package utils
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// One row of the table i.e. `MEM_DECOMMIT`, `0x00004000` and its meaning
+type ValueDefinition struct {
+	Name string
+	// Value as written, empty when table does not give it
+	ValueText string
+	Value     int64
+	// False when ValueText is empty or is not a simple integer literal
+	Resolved    bool
+	Description string
+}
+
+// Parses C integer literal, with suffixes like `u`, `L` or `ui64`. Hex, octal and negative values are supported.
+func ParseCInteger(literal string) (int64, bool) {
+	literal = strings.ToLower(strings.TrimSpace(literal))
+	literal = strings.Trim(literal, "()")
+	literal = strings.TrimSuffix(literal, "i64")
+	literal = strings.TrimSuffix(literal, "i32")
+	literal = strings.TrimRight(literal, "ul")
+
+	if v, er := strconv.ParseInt(literal, 0, 64); er == nil {
+		return v, true
+	}
+	if u, er := strconv.ParseUint(literal, 0, 64); er == nil {
+		return int64(u), true
+	}
+	return 0, false
+}
+
+// Extracts all the constants from the table, rows which do not have a name or value in first cell are skipped.
+// Found is false when the block is not a table with two columns.
+func HandleValueTable(table *goquery.Selection) (found bool, output []ValueDefinition) {
+	if !table.Is("table") {
+		return
+	}
+	for _, row := range table.Find("tr").EachIter() {
+		cells := row.ChildrenFiltered("td")
+		// Header rows only have `th`
+		if cells.Length() == 0 {
+			continue
+		}
+		if cells.Length() != 2 {
+			return false, nil
+		}
+		found = true
+
+		var (
+			first      = cells.Eq(0)
+			definition ValueDefinition
+			terms      []string
+		)
+		for _, term := range first.Find("dt").EachIter() {
+			if text := strings.Join(strings.Fields(term.Text()), " "); text != "" {
+				terms = append(terms, text)
+			}
+		}
+		// Bold term is the name, the other one is value: `<dt><b>MEM_DECOMMIT</b></dt><dt>0x00004000</dt>`
+		name := strings.TrimSpace(first.Find("b, strong").First().Text())
+		if len(terms) == 0 {
+			text := strings.Join(strings.Fields(first.Text()), " ")
+			if name != "" {
+				terms = append(terms, name)
+				text = strings.TrimSpace(strings.Replace(text, name, "", 1))
+			}
+			if text != "" {
+				terms = append(terms, text)
+			}
+		}
+		if len(terms) == 0 {
+			continue
+		}
+
+		for _, term := range terms {
+			if term == name {
+				continue
+			}
+			if _, ok := ParseCInteger(term); ok || definition.ValueText == "" && name != "" {
+				definition.ValueText = term
+				break
+			}
+		}
+		if name == "" {
+			// Table only lists values like `<dt>0</dt>`
+			name = terms[0]
+			if _, ok := ParseCInteger(name); ok {
+				definition.ValueText, name = name, ""
+			}
+		}
+		definition.Name = name
+		definition.Value, definition.Resolved = ParseCInteger(definition.ValueText)
+
+		if htm, er := cells.Eq(1).Html(); er == nil {
+			definition.Description = strings.TrimSpace(htm)
+		}
+		output = append(output, definition)
+	}
+	return
+}